@@ -0,0 +1,10 @@
+package constants
+
+import "time"
+
+// Soft deleted events and users can be restored during the RestoreWindow and
+// are purged for good once the RetentionWindow passes.
+const (
+	RestoreWindow   = 14 * 24 * time.Hour
+	RetentionWindow = 30 * 24 * time.Hour
+)
@@ -0,0 +1,16 @@
+CREATE TABLE event_images (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    public_id varchar(12) NOT NULL UNIQUE,
+    event_id varchar(12) NOT NULL REFERENCES events (public_id) ON DELETE CASCADE,
+    kind varchar(10) NOT NULL CHECK (kind IN ('cover', 'gallery')),
+    image_key TEXT NOT NULL,
+    image_url TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    thumbnail_url TEXT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_event_images_single_cover ON event_images (event_id) WHERE kind = 'cover';
+CREATE INDEX idx_event_images_event_id ON event_images (event_id);
@@ -0,0 +1,15 @@
+CREATE TABLE event_comments (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    public_id varchar(12) NOT NULL UNIQUE,
+    event_id varchar(12) NOT NULL REFERENCES events (public_id) ON DELETE CASCADE,
+    user_id varchar(12) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    parent_id varchar(12) DEFAULT NULL REFERENCES event_comments (public_id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE INDEX idx_event_comments_event_id ON event_comments (event_id, created_at);
+CREATE INDEX idx_event_comments_parent_id ON event_comments (parent_id);
@@ -0,0 +1,14 @@
+CREATE TABLE event_roles (
+    event_id varchar(12) NOT NULL REFERENCES events (public_id) ON DELETE CASCADE,
+    user_id varchar(12) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role varchar(20) NOT NULL CHECK (role IN ('owner', 'co-organizer', 'moderator')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (event_id, user_id)
+);
+
+CREATE UNIQUE INDEX idx_event_roles_single_owner ON event_roles (event_id) WHERE role = 'owner';
+CREATE INDEX idx_event_roles_user_id ON event_roles (user_id);
+
+INSERT INTO event_roles (event_id, user_id, role, created_at)
+SELECT public_id, owner_id, 'owner', created_at FROM events
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,19 @@
+CREATE TABLE event_templates (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    public_id varchar(12) NOT NULL UNIQUE,
+    owner_id varchar(12) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name varchar(30) NOT NULL,
+    sport varchar(20) NOT NULL,
+    location varchar(50) NOT NULL,
+    latitude DOUBLE PRECISION DEFAULT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION DEFAULT NULL CHECK (longitude BETWEEN -180 AND 180),
+    price smallint NOT NULL DEFAULT 0,
+    description TEXT NOT NULL DEFAULT '',
+    level varchar(30) NOT NULL,
+    capacity INT DEFAULT NULL CHECK (capacity > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT event_templates_coordinates_pair CHECK ((latitude IS NULL) = (longitude IS NULL))
+);
+
+CREATE INDEX idx_event_templates_owner_id ON event_templates (owner_id);
@@ -0,0 +1,8 @@
+ALTER TABLE events
+ADD COLUMN deleted_at TIMESTAMP DEFAULT NULL;
+
+ALTER TABLE users
+ADD COLUMN deleted_at TIMESTAMP DEFAULT NULL;
+
+CREATE INDEX idx_events_deleted_at ON events (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,30 @@
+ALTER TABLE events
+ADD COLUMN timezone varchar(64) NOT NULL DEFAULT 'Europe/Prague',
+ADD COLUMN starts_at TIMESTAMPTZ DEFAULT NULL,
+ADD COLUMN ends_at TIMESTAMPTZ DEFAULT NULL;
+
+-- the old dates had no time of day, so events start at local midnight
+UPDATE events SET starts_at = date::timestamp AT TIME ZONE timezone WHERE date IS NOT NULL;
+
+-- date stays as the local calendar day of the start, derived from starts_at,
+-- which keeps date filters and recurrence rules working on local days
+ALTER TABLE events
+DROP COLUMN date,
+ADD COLUMN date DATE GENERATED ALWAYS AS ((starts_at AT TIME ZONE timezone)::date) STORED,
+ADD CONSTRAINT events_ends_after_start CHECK (ends_at IS NULL OR ends_at > starts_at);
+
+CREATE INDEX idx_events_starts_at ON events (starts_at);
+CREATE INDEX idx_events_date ON events (date);
+
+ALTER TABLE event_occurrences
+ADD COLUMN starts_at TIMESTAMPTZ DEFAULT NULL,
+ADD COLUMN ends_at TIMESTAMPTZ DEFAULT NULL;
+
+UPDATE event_occurrences
+SET starts_at = event_occurrences.date::timestamp AT TIME ZONE events.timezone
+FROM events
+WHERE events.public_id = event_occurrences.event_id AND event_occurrences.date IS NOT NULL;
+
+ALTER TABLE event_occurrences
+DROP COLUMN date,
+ADD CONSTRAINT event_occurrences_ends_after_start CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at);
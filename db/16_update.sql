@@ -0,0 +1,49 @@
+-- the original sports table was never used, it becomes a reference like levels
+DROP TABLE IF EXISTS sports;
+
+CREATE TABLE sports (
+    id SERIAL PRIMARY KEY,
+    value VARCHAR(20) NOT NULL UNIQUE,
+    label VARCHAR(50) NOT NULL
+);
+
+INSERT INTO sports (value, label)
+VALUES
+    ('basketball', 'Basketbal'),
+    ('floorball', 'Florbal'),
+    ('football', 'Fotbal'),
+    ('volleyball', 'Volejbal'),
+    ('handball', 'Házená'),
+    ('hockey', 'Hokej'),
+    ('tennis', 'Tenis'),
+    ('table-tennis', 'Stolní tenis'),
+    ('badminton', 'Badminton'),
+    ('squash', 'Squash'),
+    ('running', 'Běh'),
+    ('cycling', 'Cyklistika'),
+    ('swimming', 'Plavání'),
+    ('climbing', 'Lezení'),
+    ('other', 'Jiné');
+
+ALTER TABLE levels ADD CONSTRAINT levels_value_unique UNIQUE (value);
+
+-- free text written before the validation is mapped onto the references
+-- where the value or the label matches, the rest is fixed on the next edit
+UPDATE events SET sport = sports.value
+FROM sports
+WHERE lower(immutable_unaccent(events.sport)) IN (sports.value, lower(immutable_unaccent(sports.label)));
+
+UPDATE events SET level = levels.value
+FROM levels
+WHERE lower(immutable_unaccent(events.level)) IN (levels.value, lower(immutable_unaccent(levels.label)));
+
+UPDATE event_templates SET sport = sports.value
+FROM sports
+WHERE lower(immutable_unaccent(event_templates.sport)) IN (sports.value, lower(immutable_unaccent(sports.label)));
+
+UPDATE event_templates SET level = levels.value
+FROM levels
+WHERE lower(immutable_unaccent(event_templates.level)) IN (levels.value, lower(immutable_unaccent(levels.label)));
+
+-- template names become event names, so they get the same limit
+ALTER TABLE event_templates ALTER COLUMN name TYPE VARCHAR(100);
@@ -0,0 +1,13 @@
+-- descriptions are sanitized on write from now on, the stored ones keep only
+-- the allowlisted formatting tags of utils.DescriptionTags without attributes
+UPDATE events
+SET description = regexp_replace(description, '<(?!/?(b|strong|i|em|u|p|br|ul|ol|li)\s*/?>)[^>]*>', '', 'gi')
+WHERE description ~ '<';
+
+UPDATE event_occurrences
+SET description = regexp_replace(description, '<(?!/?(b|strong|i|em|u|p|br|ul|ol|li)\s*/?>)[^>]*>', '', 'gi')
+WHERE description ~ '<';
+
+UPDATE event_templates
+SET description = regexp_replace(description, '<(?!/?(b|strong|i|em|u|p|br|ul|ol|li)\s*/?>)[^>]*>', '', 'gi')
+WHERE description ~ '<';
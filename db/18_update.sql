@@ -0,0 +1,12 @@
+CREATE TABLE tags (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    value varchar(30) NOT NULL UNIQUE
+);
+
+CREATE TABLE event_tags (
+    event_id varchar(12) NOT NULL REFERENCES events (public_id) ON DELETE CASCADE,
+    tag_id INT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (event_id, tag_id)
+);
+
+CREATE INDEX idx_event_tags_tag_id ON event_tags (tag_id);
@@ -0,0 +1,9 @@
+ALTER TABLE email_requests
+ADD COLUMN ticket_id varchar(12) UNIQUE DEFAULT NULL,
+ADD COLUMN checked_in_at TIMESTAMPTZ DEFAULT NULL,
+ADD COLUMN checked_in_by varchar(12) DEFAULT NULL;
+
+-- requests approved before tickets existed get one too
+UPDATE email_requests
+SET ticket_id = SUBSTRING(MD5(RANDOM()::TEXT) FROM 1 FOR 12)
+WHERE approved = true;
@@ -0,0 +1,10 @@
+ALTER TABLE email_requests
+ADD COLUMN attendance varchar(10) DEFAULT NULL CHECK (attendance IN ('attended', 'no-show')),
+ADD COLUMN attendance_marked_at TIMESTAMPTZ DEFAULT NULL;
+
+-- scanned tickets already prove the participant came
+UPDATE email_requests
+SET attendance = 'attended', attendance_marked_at = checked_in_at
+WHERE checked_in_at IS NOT NULL;
+
+CREATE INDEX idx_email_requests_requester_attendance ON email_requests (requester_id, attendance);
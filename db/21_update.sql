@@ -0,0 +1,26 @@
+CREATE TABLE venues (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    public_id varchar(12) NOT NULL UNIQUE,
+    owner_id varchar(12) NOT NULL,
+    name varchar(50) NOT NULL,
+    street varchar(100) NOT NULL DEFAULT '',
+    city varchar(50) NOT NULL,
+    postal_code varchar(12) NOT NULL DEFAULT '',
+    country_code char(2) NOT NULL DEFAULT 'CZ',
+    latitude DOUBLE PRECISION DEFAULT NULL,
+    longitude DOUBLE PRECISION DEFAULT NULL,
+    facilities TEXT[] NOT NULL DEFAULT '{}',
+    indoor BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_venues_city ON venues (LOWER(city));
+
+-- events keep copying the venue name and coordinates into location, latitude
+-- and longitude, so search and distance filters work unchanged and a deleted
+-- venue leaves the free text behind
+ALTER TABLE events
+ADD COLUMN venue_id varchar(12) DEFAULT NULL REFERENCES venues (public_id) ON DELETE SET NULL;
+
+CREATE INDEX idx_events_venue_id ON events (venue_id);
@@ -0,0 +1,6 @@
+-- venues go away with the user who created them, their events keep the copied
+-- location through ON DELETE SET NULL on events.venue_id
+DELETE FROM venues WHERE owner_id NOT IN (SELECT id FROM users);
+
+ALTER TABLE venues
+ADD CONSTRAINT venues_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE CASCADE;
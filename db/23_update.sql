@@ -0,0 +1,37 @@
+-- text nodes of descriptions were stored with escaped quotes, which only
+-- attributes need
+UPDATE events
+SET description = replace(replace(description, '&#34;', '"'), '&#39;', '''')
+WHERE description LIKE '%&#%';
+
+UPDATE event_occurrences
+SET description = replace(replace(description, '&#34;', '"'), '&#39;', '''')
+WHERE description LIKE '%&#%';
+
+UPDATE event_templates
+SET description = replace(replace(description, '&#34;', '"'), '&#39;', '''')
+WHERE description LIKE '%&#%';
+
+-- the search vector indexes the text of the description, so entities are
+-- decoded as well, &amp; last so it does not create new entities
+CREATE OR REPLACE FUNCTION strip_html(text)
+RETURNS text AS $$
+    SELECT replace(replace(replace(replace(replace(replace(replace(
+        regexp_replace($1, '<[^>]*>', ' ', 'g'),
+        '&nbsp;', ' '), '&quot;', '"'), '&#34;', '"'), '&#39;', ''''), '&lt;', '<'), '&gt;', '>'), '&amp;', '&')
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+-- a stored generated column is not recomputed when its function changes
+DROP INDEX idx_events_search_vector;
+
+ALTER TABLE events DROP COLUMN search_vector;
+
+ALTER TABLE events
+ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', immutable_unaccent(coalesce(name, ''))), 'A') ||
+    setweight(to_tsvector('simple', immutable_unaccent(coalesce(sport, ''))), 'B') ||
+    setweight(to_tsvector('simple', immutable_unaccent(coalesce(location, ''))), 'B') ||
+    setweight(to_tsvector('simple', immutable_unaccent(strip_html(coalesce(description, '')))), 'C')
+) STORED;
+
+CREATE INDEX idx_events_search_vector ON events USING GIN (search_vector);
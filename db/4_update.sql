@@ -0,0 +1,22 @@
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent() is only STABLE, generated columns and indexes need IMMUTABLE
+CREATE OR REPLACE FUNCTION immutable_unaccent(text)
+RETURNS text AS $$
+    SELECT public.unaccent('public.unaccent', $1)
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+CREATE OR REPLACE FUNCTION strip_html(text)
+RETURNS text AS $$
+    SELECT regexp_replace($1, '<[^>]*>', ' ', 'g')
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+ALTER TABLE events
+ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', immutable_unaccent(coalesce(name, ''))), 'A') ||
+    setweight(to_tsvector('simple', immutable_unaccent(coalesce(sport, ''))), 'B') ||
+    setweight(to_tsvector('simple', immutable_unaccent(coalesce(location, ''))), 'B') ||
+    setweight(to_tsvector('simple', immutable_unaccent(strip_html(coalesce(description, '')))), 'C')
+) STORED;
+
+CREATE INDEX idx_events_search_vector ON events USING GIN (search_vector);
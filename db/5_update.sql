@@ -0,0 +1,6 @@
+ALTER TABLE events
+ADD COLUMN latitude DOUBLE PRECISION DEFAULT NULL CHECK (latitude BETWEEN -90 AND 90),
+ADD COLUMN longitude DOUBLE PRECISION DEFAULT NULL CHECK (longitude BETWEEN -180 AND 180),
+ADD CONSTRAINT events_coordinates_pair CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+CREATE INDEX idx_events_coordinates ON events (latitude, longitude);
@@ -0,0 +1,10 @@
+ALTER TABLE events
+ADD COLUMN capacity INT DEFAULT NULL CHECK (capacity > 0);
+
+CREATE TABLE event_participants (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    event_id varchar(12) NOT NULL REFERENCES events (public_id) ON DELETE CASCADE,
+    user_id varchar(12) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT event_participants_unique UNIQUE (event_id, user_id)
+);
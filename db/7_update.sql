@@ -0,0 +1,17 @@
+ALTER TABLE events
+ADD COLUMN recurrence_rule TEXT DEFAULT NULL,
+ADD COLUMN recurrence_exdates DATE[] NOT NULL DEFAULT '{}',
+ADD COLUMN recurrence_end DATE DEFAULT NULL;
+
+CREATE TABLE event_occurrences (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    event_id varchar(12) NOT NULL REFERENCES events (public_id) ON DELETE CASCADE,
+    occurrence_date DATE NOT NULL,
+    name varchar(100) DEFAULT NULL,
+    date DATE DEFAULT NULL,
+    location varchar(50) DEFAULT NULL,
+    price smallint DEFAULT NULL,
+    description TEXT DEFAULT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT event_occurrences_unique UNIQUE (event_id, occurrence_date)
+);
@@ -0,0 +1,6 @@
+CREATE TABLE calendar_tokens (
+    user_id varchar(12) PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    token_hash varchar(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT calendar_tokens_hash_unique UNIQUE (token_hash)
+);
@@ -0,0 +1,7 @@
+ALTER TABLE events
+ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'published', 'cancelled', 'completed')),
+ADD COLUMN cancellation_reason TEXT DEFAULT NULL,
+ADD COLUMN status_changed_at TIMESTAMP DEFAULT NULL;
+
+CREATE INDEX idx_events_status ON events (status);
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+)
+
+// ActiveUser rejects tokens of soft deleted users, which stay valid until
+// they expire. It has to run after JwtAuth.
+func ActiveUser(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var active bool
+		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)", c.GetString(constants.UserID_key)).Scan(&active)
+		if err != nil || !active {
+			log.Println("(ActiveUser) db.QueryRow", err)
+			c.String(http.StatusUnauthorized, "Unauthorized")
+			c.Abort()
+
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	AttendanceAttended = "attended"
+	AttendanceNoShow   = "no-show"
+)
+
+type AttendanceInput struct {
+	Attendance string `json:"attendance" example:"attended" enums:"attended,no-show"`
+}
+
+type AttendanceRecord struct {
+	RequestID     uint16     `json:"requestId" example:"1"`
+	EventID       string     `json:"eventId" example:"pwnrxtbi9z0v"`
+	EventName     string     `json:"eventName" example:"Sample Event"`
+	EventTimezone string     `json:"eventTimezone" example:"Europe/Prague"`
+	EventStartsAt time.Time  `json:"eventStartsAt" example:"2023-11-03T18:00:00+01:00"`
+	Attendance    *string    `json:"attendance" example:"attended" enums:"attended,no-show"`
+	CheckedInAt   *time.Time `json:"checkedInAt,omitempty" example:"2023-11-03T18:05:00Z"`
+}
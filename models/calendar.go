@@ -0,0 +1,6 @@
+package models
+
+type CalendarTokenResponse struct {
+	Token string `json:"token" example:"3f1c0d9e6b7a4c2d8e5f9a0b1c2d3e4f"`
+	URL   string `json:"url" example:"https://sportujspolu-api.onrender.com/api/v1/calendar/3f1c0d9e6b7a4c2d8e5f9a0b1c2d3e4f.ics"`
+}
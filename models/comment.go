@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type Comment struct {
+	ID         string     `json:"id" example:"pwnrxtbi9z0v"`
+	EventID    string     `json:"eventId" example:"q76j5d1a3xtn"`
+	ParentID   *string    `json:"parentId,omitempty" example:"pwnrxtbi9z0v"`
+	AuthorID   string     `json:"authorId" example:"pwnrxtbi9z0v"`
+	AuthorName string     `json:"authorName" example:"John Doe"`
+	Text       string     `json:"text" example:"Is parking available?"`
+	Hidden     bool       `json:"hidden" example:"false"`
+	Pinned     bool       `json:"pinned" example:"false"`
+	CreatedAt  time.Time  `json:"createdAt" example:"2023-11-03T10:15:30Z"`
+	EditedAt   *time.Time `json:"editedAt,omitempty" example:"2023-11-03T10:15:30Z"`
+	Replies    []Comment  `json:"replies,omitempty"`
+}
+
+type CommentInput struct {
+	Text     string  `json:"text" example:"Is parking available?"`
+	ParentID *string `json:"parentId,omitempty" example:"pwnrxtbi9z0v"`
+}
+
+type CommentUpdateInput struct {
+	Text string `json:"text" example:"Is parking available nearby?"`
+}
+
+type CommentModerationInput struct {
+	Hidden *bool `json:"hidden,omitempty" example:"true"`
+	Pinned *bool `json:"pinned,omitempty" example:"false"`
+}
@@ -3,3 +3,13 @@ package models
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
+
+type FieldError struct {
+	Field   string `json:"field" example:"level"`
+	Message string `json:"message" example:"level must be one of beginner, advanced, expert, any"`
+}
+
+type ValidationErrorResponse struct {
+	Error  string       `json:"error" example:"Invalid event"`
+	Fields []FieldError `json:"fields"`
+}
@@ -2,31 +2,86 @@ package models
 
 import "time"
 
+const (
+	EventStatusDraft     = "draft"
+	EventStatusPublished = "published"
+	EventStatusCancelled = "cancelled"
+	EventStatusCompleted = "completed"
+)
+
 type Event struct {
-	ID          uint16    `json:"-"`
-	Public_ID   string    `json:"id" example:"pwnrxtbi9z0v"`
-	Name        string    `json:"name" example:"Basketball Match at Park"`
-	Sport       string    `json:"sport" example:"Basketball"`
-	Date        time.Time `json:"date" example:"2023-11-03T10:15:30Z"`
-	Location    string    `json:"location" example:"Central Park"`
-	Price       uint16    `json:"price" example:"123"`
-	Description string    `json:"description" example:"Example Description"`
-	Level       string    `json:"level" example:"Any"`
-	Created_At  time.Time `json:"createdAt" example:"2023-11-03T10:15:30Z"`
-	Owner_ID    string    `json:"ownerId" example:"pwnrxtbi9z0v"`
+	ID                 int          `json:"-"`
+	Public_ID          string       `json:"id" example:"pwnrxtbi9z0v"`
+	Name               string       `json:"name" example:"Basketball Match at Park"`
+	Sport              string       `json:"sport" example:"basketball"`
+	StartsAt           time.Time    `json:"startsAt" example:"2023-11-03T18:00:00+01:00"`
+	EndsAt             *time.Time   `json:"endsAt,omitempty" example:"2023-11-03T20:00:00+01:00"`
+	Timezone           string       `json:"timezone" example:"Europe/Prague"`
+	Location           string       `json:"location" example:"Central Park"`
+	VenueID            *string      `json:"venueId,omitempty" example:"v7k2m9xq4pza"`
+	Latitude           *float64     `json:"latitude,omitempty" example:"49.1951"`
+	Longitude          *float64     `json:"longitude,omitempty" example:"16.6068"`
+	Price              uint16       `json:"price" example:"123"`
+	Description        string       `json:"description" example:"Example <b>Description</b>"`
+	DescriptionText    string       `json:"descriptionText" example:"Example Description"`
+	Level              string       `json:"level" example:"any"`
+	Capacity           *uint16      `json:"capacity,omitempty" example:"10"`
+	RecurrenceRule     *string      `json:"recurrenceRule,omitempty" example:"FREQ=WEEKLY;BYDAY=TU"`
+	ExceptionDates     []string     `json:"exceptionDates,omitempty" example:"2024-01-02"`
+	Tags               []string     `json:"tags" example:"outdoor,family friendly"`
+	Status             string       `json:"status" example:"published" enums:"draft,published,cancelled,completed"`
+	CancellationReason *string      `json:"cancellationReason,omitempty" example:"Not enough players"`
+	CoverImageURL      *string      `json:"coverImageUrl,omitempty" example:"/uploads/events/pwnrxtbi9z0v/q76j5d1a3xtn.jpg"`
+	CoverThumbnailURL  *string      `json:"coverThumbnailUrl,omitempty" example:"/uploads/events/pwnrxtbi9z0v/q76j5d1a3xtn_thumb.jpg"`
+	Gallery            []EventImage `json:"gallery,omitempty"`
+	Created_At         time.Time    `json:"createdAt" example:"2023-11-03T10:15:30Z"`
+	Owner_ID           string       `json:"ownerId" example:"pwnrxtbi9z0v"`
 }
 
 type EventWithOwner struct {
 	Event
-	Owner *PublicUser `json:"owner,omitempty" swaggertype:"object,string" example:"id:pwnrxtbi9z0v,name:John Doe,email:email@test.com,rating:3"`
+	Owner          *PublicUser `json:"owner,omitempty" swaggertype:"object,string" example:"id:pwnrxtbi9z0v,name:John Doe,email:email@test.com,rating:3"`
+	DistanceKm     *float64    `json:"distanceKm,omitempty" example:"2.4"`
+	SpotsLeft      *int        `json:"spotsLeft,omitempty" example:"4"`
+	OccurrenceDate *string     `json:"occurrenceDate,omitempty" example:"2024-01-09"`
+	Venue          *Venue      `json:"venue,omitempty"`
+}
+
+type EventPage struct {
+	Data       []EventWithOwner `json:"data"`
+	Total      *int             `json:"total,omitempty" example:"42"`
+	NextCursor *string          `json:"nextCursor,omitempty" example:"eyJjIjoiMjAyMy0xMS0wM1QxMDoxNTozMFoiLCJpIjo0Nn0"`
 }
 
 type EventInput struct {
-	Name        string    `json:"name" example:"Basketball Match at Park"`
-	Sport       string    `json:"sport" example:"Basketball"`
-	Date        time.Time `json:"date" example:"2023-11-03T10:15:30Z"`
-	Location    string    `json:"location" example:"Central Park"`
-	Price       uint16    `json:"price" example:"123"`
-	Description string    `json:"description" example:"Example Description"`
-	Level       string    `json:"level" example:"Any"`
+	Name           string     `json:"name" example:"Basketball Match at Park"`
+	Sport          string     `json:"sport" example:"basketball"`
+	StartsAt       time.Time  `json:"startsAt" example:"2023-11-03T18:00:00+01:00"`
+	EndsAt         *time.Time `json:"endsAt,omitempty" example:"2023-11-03T20:00:00+01:00"`
+	Timezone       string     `json:"timezone,omitempty" example:"Europe/Prague"`
+	Location       string     `json:"location" example:"Central Park"`
+	VenueID        *string    `json:"venueId,omitempty" example:"v7k2m9xq4pza"`
+	Latitude       *float64   `json:"latitude,omitempty" example:"49.1951"`
+	Longitude      *float64   `json:"longitude,omitempty" example:"16.6068"`
+	Price          uint16     `json:"price" example:"123"`
+	Description    string     `json:"description" example:"Example Description"`
+	Level          string     `json:"level" example:"any"`
+	Capacity       *uint16    `json:"capacity,omitempty" example:"10"`
+	RecurrenceRule *string    `json:"recurrenceRule,omitempty" example:"FREQ=WEEKLY;BYDAY=TU"`
+	ExceptionDates []string   `json:"exceptionDates,omitempty" example:"2024-01-02"`
+	Tags           []string   `json:"tags,omitempty" example:"outdoor,family friendly"`
+	Status         string     `json:"status,omitempty" example:"draft" enums:"draft,published"`
+}
+
+type EventCancelInput struct {
+	Reason string `json:"reason" example:"Not enough players"`
+}
+
+type OccurrenceInput struct {
+	Name        *string    `json:"name,omitempty" example:"Basketball Match at Park"`
+	StartsAt    *time.Time `json:"startsAt,omitempty" example:"2023-11-03T18:00:00+01:00"`
+	EndsAt      *time.Time `json:"endsAt,omitempty" example:"2023-11-03T20:00:00+01:00"`
+	Location    *string    `json:"location,omitempty" example:"Central Park"`
+	Price       *uint16    `json:"price,omitempty" example:"123"`
+	Description *string    `json:"description,omitempty" example:"Example Description"`
 }
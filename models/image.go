@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type EventImage struct {
+	ID           string    `json:"id" example:"pwnrxtbi9z0v"`
+	URL          string    `json:"url" example:"/uploads/events/pwnrxtbi9z0v/q76j5d1a3xtn.jpg"`
+	ThumbnailURL string    `json:"thumbnailUrl" example:"/uploads/events/pwnrxtbi9z0v/q76j5d1a3xtn_thumb.jpg"`
+	Width        int       `json:"width" example:"1920"`
+	Height       int       `json:"height" example:"1080"`
+	CreatedAt    time.Time `json:"createdAt" example:"2023-11-03T10:15:30Z"`
+}
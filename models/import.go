@@ -0,0 +1,17 @@
+package models
+
+type EventImportRow struct {
+	// Row is the line of the CSV file, header included, or the position in
+	// the JSON array starting at 1
+	Row     int          `json:"row" example:"2"`
+	EventID *string      `json:"eventId,omitempty" example:"pwnrxtbi9z0v"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+type EventImportResult struct {
+	DryRun bool `json:"dryRun" example:"false"`
+	// Created counts the rows that would be created in a dry run
+	Created  int              `json:"created" example:"12"`
+	Rejected int              `json:"rejected" example:"1"`
+	Rows     []EventImportRow `json:"rows"`
+}
@@ -12,6 +12,8 @@ type EmailRequest struct {
 	RequesterID  string     `json:"requesterId" example:"pwnrxtbi9z0v"`
 	Approved     *bool      `json:"approved" example:"false"`
 	ApprovedAt   *time.Time `json:"approvedAt,omitempty" example:"2023-11-03T10:15:30Z"`
+	CheckedInAt  *time.Time `json:"checkedInAt,omitempty" example:"2023-11-03T18:05:00Z"`
+	Attendance   *string    `json:"attendance,omitempty" example:"attended" enums:"attended,no-show"`
 	CreatedAt    time.Time  `json:"createdAt" example:"2023-11-03T10:15:30Z"`
 	UpdatedAt    time.Time  `json:"updatedAt" example:"2023-11-03T10:15:30Z"`
 }
@@ -37,10 +39,21 @@ type EmailRequestResponse struct {
 	RequesterName  *string `json:"requesterName,omitempty" example:"John Doe"`
 	RequesterEmail *string `json:"requesterEmail,omitempty" example:"email@test.com"`
 
+	RequesterAttended    *int     `json:"requesterAttended,omitempty" example:"9"`
+	RequesterNoShows     *int     `json:"requesterNoShows,omitempty" example:"1"`
+	RequesterReliability *float64 `json:"requesterReliability,omitempty" example:"0.9"`
+
 	EventOwnerName  *string `json:"eventOwnerName,omitempty" example:"Owner Name"`
 	EventOwnerEmail *string `json:"eventOwnerEmail,omitempty" example:"email@test.com"`
 	EventName       *string `json:"eventName,omitempty" example:"Sample Event"`
 	EventLocation   *string `json:"eventLocation,omitempty" example:"Central Park"`
-	EventLevel      *string `json:"eventLevel,omitempty" example:"Any"`
-	EventSport      *string `json:"eventSport,omitempty" example:"Basketball"`
+	EventLevel      *string `json:"eventLevel,omitempty" example:"any"`
+	EventSport      *string `json:"eventSport,omitempty" example:"basketball"`
+	EventStatus     *string `json:"eventStatus,omitempty" example:"cancelled"`
+	EventTimezone   *string `json:"eventTimezone,omitempty" example:"Europe/Prague"`
+
+	EventStartsAt *time.Time `json:"eventStartsAt,omitempty" example:"2023-11-03T18:00:00+01:00"`
+	EventEndsAt   *time.Time `json:"eventEndsAt,omitempty" example:"2023-11-03T20:00:00+01:00"`
+
+	EventCancellationReason *string `json:"eventCancellationReason,omitempty" example:"Not enough players"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type Participant struct {
+	UserID   string    `json:"userId" example:"pwnrxtbi9z0v"`
+	Name     string    `json:"name" example:"John Doe"`
+	Email    string    `json:"email" example:"email@test.com"`
+	JoinedAt time.Time `json:"joinedAt" example:"2023-11-03T10:15:30Z"`
+}
+
+type ParticipationResponse struct {
+	EventID   string    `json:"eventId" example:"pwnrxtbi9z0v"`
+	UserID    string    `json:"userId" example:"pwnrxtbi9z0v"`
+	JoinedAt  time.Time `json:"joinedAt" example:"2023-11-03T10:15:30Z"`
+	SpotsLeft *int      `json:"spotsLeft,omitempty" example:"4"`
+}
@@ -5,3 +5,14 @@ type Level struct {
 	Value string `json:"value" example:"beginner"`
 	Label string `json:"label" example:"Beginner"`
 }
+
+type Sport struct {
+	ID    int    `json:"id" example:"1"`
+	Value string `json:"value" example:"basketball"`
+	Label string `json:"label" example:"Basketbal"`
+}
+
+type Tag struct {
+	Value string `json:"value" example:"outdoor"`
+	Count int    `json:"count" example:"12"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	EventRoleOwner       = "owner"
+	EventRoleCoOrganizer = "co-organizer"
+	EventRoleModerator   = "moderator"
+)
+
+type EventRole struct {
+	UserID    string    `json:"userId" example:"pwnrxtbi9z0v"`
+	Name      string    `json:"name" example:"John Doe"`
+	Email     string    `json:"email" example:"email@test.com"`
+	Role      string    `json:"role" example:"co-organizer" enums:"owner,co-organizer,moderator"`
+	CreatedAt time.Time `json:"createdAt" example:"2023-11-03T10:15:30Z"`
+}
+
+type EventRoleInput struct {
+	Email string `json:"email" example:"email@test.com"`
+	Role  string `json:"role" example:"co-organizer" enums:"co-organizer,moderator"`
+}
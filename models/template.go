@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type EventTemplate struct {
+	ID          string    `json:"id" example:"pwnrxtbi9z0v"`
+	Name        string    `json:"name" example:"Tuesday Basketball"`
+	Sport       string    `json:"sport" example:"basketball"`
+	Location    string    `json:"location" example:"Central Park"`
+	Latitude    *float64  `json:"latitude,omitempty" example:"49.1951"`
+	Longitude   *float64  `json:"longitude,omitempty" example:"16.6068"`
+	Price       uint16    `json:"price" example:"123"`
+	Description string    `json:"description" example:"Example Description"`
+	Level       string    `json:"level" example:"any"`
+	Capacity    *uint16   `json:"capacity,omitempty" example:"10"`
+	CreatedAt   time.Time `json:"createdAt" example:"2023-11-03T10:15:30Z"`
+	UpdatedAt   time.Time `json:"updatedAt" example:"2023-11-03T10:15:30Z"`
+}
+
+type EventTemplateInput struct {
+	Name        string   `json:"name" example:"Tuesday Basketball"`
+	Sport       string   `json:"sport" example:"basketball"`
+	Location    string   `json:"location" example:"Central Park"`
+	Latitude    *float64 `json:"latitude,omitempty" example:"49.1951"`
+	Longitude   *float64 `json:"longitude,omitempty" example:"16.6068"`
+	Price       uint16   `json:"price" example:"123"`
+	Description string   `json:"description" example:"Example Description"`
+	Level       string   `json:"level" example:"any"`
+	Capacity    *uint16  `json:"capacity,omitempty" example:"10"`
+}
+
+// NewEventInput is the part of an event that is not copied when an event is
+// created from a template or cloned.
+type NewEventInput struct {
+	StartsAt time.Time  `json:"startsAt" example:"2023-11-10T18:00:00+01:00"`
+	EndsAt   *time.Time `json:"endsAt,omitempty" example:"2023-11-10T20:00:00+01:00"`
+	Timezone string     `json:"timezone,omitempty" example:"Europe/Prague"`
+	Name     *string    `json:"name,omitempty" example:"Basketball Match at Park"`
+	Status   string     `json:"status,omitempty" example:"draft" enums:"draft,published"`
+}
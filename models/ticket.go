@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type Ticket struct {
+	EventID     string     `json:"eventId" example:"pwnrxtbi9z0v"`
+	TicketID    string     `json:"ticketId" example:"h3k9x0pq2mza"`
+	Token       string     `json:"token" example:"pwnrxtbi9z0v.h3k9x0pq2mza.2fQm8yS1kq0bW5pXr7cJ3nVd9LhT6uEaZoYiGxKwBsM"`
+	CheckedInAt *time.Time `json:"checkedInAt,omitempty" example:"2023-11-03T18:05:00Z"`
+}
+
+type CheckInInput struct {
+	Token string `json:"token" example:"pwnrxtbi9z0v.h3k9x0pq2mza.2fQm8yS1kq0bW5pXr7cJ3nVd9LhT6uEaZoYiGxKwBsM"`
+}
+
+type CheckIn struct {
+	TicketID    string    `json:"ticketId" example:"h3k9x0pq2mza"`
+	UserID      string    `json:"userId" example:"pwnrxtbi9z0v"`
+	Name        string    `json:"name" example:"John Doe"`
+	Email       string    `json:"email" example:"email@test.com"`
+	CheckedInAt time.Time `json:"checkedInAt" example:"2023-11-03T18:05:00Z"`
+}
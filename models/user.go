@@ -13,4 +13,10 @@ type PublicUser struct {
 	Name   string `json:"name" example:"John Doe"`
 	Email  string `json:"email" example:"email@test.com"`
 	Rating int    `json:"rating" example:"3"`
+
+	Attended int `json:"attended" example:"9"`
+	NoShows  int `json:"noShows" example:"1"`
+	// Reliability is the share of marked events the user attended, null
+	// until an organizer marks the first one
+	Reliability *float64 `json:"reliability" example:"0.9"`
 }
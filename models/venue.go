@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type Venue struct {
+	ID          string    `json:"id" example:"v7k2m9xq4pza"`
+	Name        string    `json:"name" example:"Sportovní hala Lužánky"`
+	Street      string    `json:"street" example:"Lidická 1"`
+	City        string    `json:"city" example:"Brno"`
+	PostalCode  string    `json:"postalCode" example:"602 00"`
+	CountryCode string    `json:"countryCode" example:"CZ"`
+	Latitude    *float64  `json:"latitude,omitempty" example:"49.2079"`
+	Longitude   *float64  `json:"longitude,omitempty" example:"16.6056"`
+	Facilities  []string  `json:"facilities" example:"showers,parking"`
+	Indoor      bool      `json:"indoor" example:"true"`
+	OwnerID     string    `json:"ownerId" example:"pwnrxtbi9z0v"`
+	CreatedAt   time.Time `json:"createdAt" example:"2023-11-03T10:15:30Z"`
+	UpdatedAt   time.Time `json:"updatedAt" example:"2023-11-03T10:15:30Z"`
+}
+
+type VenueInput struct {
+	Name        string   `json:"name" example:"Sportovní hala Lužánky"`
+	Street      string   `json:"street" example:"Lidická 1"`
+	City        string   `json:"city" example:"Brno"`
+	PostalCode  string   `json:"postalCode" example:"602 00"`
+	CountryCode string   `json:"countryCode,omitempty" example:"CZ"`
+	Latitude    *float64 `json:"latitude,omitempty" example:"49.2079"`
+	Longitude   *float64 `json:"longitude,omitempty" example:"16.6056"`
+	Facilities  []string `json:"facilities,omitempty" example:"showers,parking"`
+	Indoor      bool     `json:"indoor" example:"true"`
+}
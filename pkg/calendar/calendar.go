@@ -0,0 +1,308 @@
+package calendar
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/pkg/roles"
+	"github.com/globus303/sportujspolu/utils"
+	"github.com/lib/pq"
+)
+
+const (
+	icalColumns      = "events.public_id, events.name, events.starts_at, events.ends_at, events.timezone, events.location, events.description, events.recurrence_rule, events.recurrence_exdates, events.created_at, events.status"
+	calendarMime     = "text/calendar; charset=utf-8"
+	tokenBytes       = 32
+	feedPathPrefix   = "/api/v1/calendar/"
+	feedFileSuffix   = ".ics"
+	feedCalendarName = "SportujSpolu"
+)
+
+type CalendarService struct {
+	db *sql.DB
+}
+
+func NewCalendarService(db *sql.DB) *CalendarService {
+	return &CalendarService{db}
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func (s *CalendarService) queryEvents(where string, args ...interface{}) ([]icalEvent, error) {
+	rows, err := s.db.Query("SELECT "+icalColumns+" FROM events WHERE events.deleted_at IS NULL AND ("+where+") ORDER BY events.starts_at", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []icalEvent{}
+	recurringIds := []string{}
+	for rows.Next() {
+		var event icalEvent
+		if err := rows.Scan(&event.PublicID, &event.Name, &event.StartsAt, &event.EndsAt, &event.Timezone, &event.Location, &event.Description, &event.RecurrenceRule, pq.Array(&event.ExceptionDates), &event.CreatedAt, &event.Status); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+		if event.RecurrenceRule != nil {
+			recurringIds = append(recurringIds, event.PublicID)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.appendEditedOccurrences(events, recurringIds)
+}
+
+// appendEditedOccurrences adds a RECURRENCE-ID event for every occurrence
+// that was edited on its own, so calendars show the changed values.
+func (s *CalendarService) appendEditedOccurrences(events []icalEvent, recurringIds []string) ([]icalEvent, error) {
+	if len(recurringIds) == 0 {
+		return events, nil
+	}
+
+	series := map[string]icalEvent{}
+	for _, event := range events {
+		series[event.PublicID] = event
+	}
+
+	query := `
+		SELECT event_id, occurrence_date, name, starts_at, ends_at, location, description
+		FROM event_occurrences
+		WHERE event_id = ANY($1)
+		ORDER BY occurrence_date
+	`
+	rows, err := s.db.Query(query, pq.Array(recurringIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventId string
+		var occurrenceDate time.Time
+		var name, location, description *string
+		var startsAt, endsAt *time.Time
+		if err := rows.Scan(&eventId, &occurrenceDate, &name, &startsAt, &endsAt, &location, &description); err != nil {
+			return nil, err
+		}
+
+		// the occurrence keeps the local start time and the duration of the
+		// series unless it was moved
+		occurrence := series[eventId]
+		start := utils.OnDay(utils.InTimezone(occurrence.StartsAt, occurrence.Timezone), occurrenceDate)
+		recurrenceID := start
+		occurrence.RecurrenceID = &recurrenceID
+		if startsAt != nil {
+			start = *startsAt
+		}
+		if occurrence.EndsAt != nil {
+			end := start.Add(occurrence.EndsAt.Sub(occurrence.StartsAt))
+			occurrence.EndsAt = &end
+		}
+		occurrence.StartsAt = start
+
+		if name != nil {
+			occurrence.Name = *name
+		}
+		if endsAt != nil {
+			occurrence.EndsAt = endsAt
+		}
+		if location != nil {
+			occurrence.Location = *location
+		}
+		if description != nil {
+			occurrence.Description = *description
+		}
+
+		events = append(events, occurrence)
+	}
+
+	return events, rows.Err()
+}
+
+func writeCalendar(c *gin.Context, filename string, name string, events []icalEvent) {
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, calendarMime, []byte(renderCalendar(name, events)))
+}
+
+// @Summary Export event to iCalendar
+// @Description Returns the event as an .ics file with a single VEVENT. Drafts are available only to their organizers, cancelled events carry STATUS:CANCELLED.
+// @Tags calendar
+// @Produce text/calendar
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Success 200 {string} string "iCalendar file"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /events/{eventId}/ical [get]
+func (s *CalendarService) GetEventICal(c *gin.Context) {
+	eventId := c.Param("eventId")
+
+	events, err := s.queryEvents("events.public_id = $1", eventId)
+	if err != nil {
+		log.Println("(GetEventICal) queryEvents", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error exporting event"))
+
+		return
+	}
+
+	if len(events) == 0 {
+		c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+		return
+	}
+
+	// drafts are visible only to their organizers, as in GetSingleEvent
+	if events[0].Status == models.EventStatusDraft {
+		userID, err := utils.TokenValid(c)
+		if err != nil {
+			c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+			return
+		}
+
+		role, err := roles.GetUserRole(s.db, eventId, userID)
+		if err != nil || role == "" {
+			c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+			return
+		}
+	}
+
+	writeCalendar(c, eventId+feedFileSuffix, events[0].Name, events)
+}
+
+// @Summary Create calendar feed token
+// @Description Creates a secret token for the personal calendar feed, replacing any previous one. The token is shown only once.
+// @Tags calendar
+// @Produce json
+// @Success 200 {object} models.CalendarTokenResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /user/me/calendar-token [post]
+func (s *CalendarService) CreateCalendarToken(c *gin.Context) {
+	userID := c.GetString(constants.UserID_key)
+
+	secret := make([]byte, tokenBytes)
+	if _, err := rand.Read(secret); err != nil {
+		log.Println("(CreateCalendarToken) rand.Read", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error creating calendar token"))
+
+		return
+	}
+	token := hex.EncodeToString(secret)
+
+	query := `
+		INSERT INTO calendar_tokens (user_id, token_hash, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = EXCLUDED.created_at
+	`
+	if _, err := s.db.Exec(query, userID, hashToken(token), time.Now()); err != nil {
+		log.Println("(CreateCalendarToken) db.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error creating calendar token"))
+
+		return
+	}
+
+	scheme := "https"
+	if c.Request.TLS == nil && c.GetHeader("X-Forwarded-Proto") != "https" {
+		scheme = "http"
+	}
+
+	c.JSON(http.StatusOK, models.CalendarTokenResponse{
+		Token: token,
+		URL:   scheme + "://" + c.Request.Host + feedPathPrefix + token + feedFileSuffix,
+	})
+}
+
+// @Summary Revoke calendar feed token
+// @Description Revokes the personal calendar feed token, the feed URL stops working immediately
+// @Tags calendar
+// @Success 200
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /user/me/calendar-token [delete]
+func (s *CalendarService) RevokeCalendarToken(c *gin.Context) {
+	userID := c.GetString(constants.UserID_key)
+
+	res, err := s.db.Exec("DELETE FROM calendar_tokens WHERE user_id = $1", userID)
+	if err != nil {
+		log.Println("(RevokeCalendarToken) db.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error revoking calendar token"))
+
+		return
+	}
+
+	if deleted, err := res.RowsAffected(); err != nil || deleted == 0 {
+		c.JSON(http.StatusNotFound, utils.GetError("Calendar token not found"))
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Personal calendar feed
+// @Description iCalendar feed with every event the token owner organizes or was approved for. Authenticated by the secret token in the URL so calendar apps can subscribe to it.
+// @Tags calendar
+// @Produce text/calendar
+// @Param token path string true "Calendar token, optionally with .ics suffix"
+// @Success 200 {string} string "iCalendar feed"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /calendar/{token} [get]
+func (s *CalendarService) GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), feedFileSuffix)
+
+	var userID string
+	query := `
+		SELECT calendar_tokens.user_id
+		FROM calendar_tokens
+		JOIN users ON users.id = calendar_tokens.user_id AND users.deleted_at IS NULL
+		WHERE calendar_tokens.token_hash = $1
+	`
+	err := s.db.QueryRow(query, hashToken(token)).Scan(&userID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Println("(GetCalendarFeed) db.QueryRow", err)
+		}
+		c.JSON(http.StatusNotFound, utils.GetError("Calendar not found"))
+
+		return
+	}
+
+	where := `events.public_id IN (
+		SELECT event_roles.event_id FROM event_roles
+		WHERE event_roles.user_id = $1 AND event_roles.role IN ` + roles.OrganizerRoles + `
+	) OR events.public_id IN (
+		SELECT email_requests.event_id FROM email_requests
+		WHERE email_requests.requester_id = $1 AND email_requests.approved = true
+	)`
+	events, err := s.queryEvents(where, userID)
+	if err != nil {
+		log.Println("(GetCalendarFeed) queryEvents", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error exporting calendar"))
+
+		return
+	}
+
+	writeCalendar(c, "sportujspolu"+feedFileSuffix, feedCalendarName, events)
+}
@@ -0,0 +1,155 @@
+package calendar
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+const (
+	isoDateLayout      = "2006-01-02"
+	icalDateTimeLayout = "20060102T150405Z"
+	icalLocalLayout    = "20060102T150405"
+	icalLineLimit      = 75
+	icalDomain         = "sportujspolu"
+)
+
+type icalEvent struct {
+	PublicID       string
+	Name           string
+	StartsAt       time.Time
+	EndsAt         *time.Time
+	Timezone       string
+	Location       string
+	Description    string
+	RecurrenceRule *string
+	ExceptionDates []string
+	CreatedAt      time.Time
+	Status         string
+	// RecurrenceID marks an edited occurrence of a recurring event
+	RecurrenceID *time.Time
+}
+
+var icalTextEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`)
+
+type icalWriter struct {
+	builder strings.Builder
+}
+
+// line writes a content line, folding it at 75 octets as RFC 5545 requires
+// without splitting multi-byte characters. Continuation lines start with a
+// space, which counts towards their 75 octets.
+func (w *icalWriter) line(content string) {
+	limit := icalLineLimit
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+
+		w.builder.WriteString(content[:cut] + "\r\n ")
+		content = content[cut:]
+		limit = icalLineLimit - 1
+	}
+
+	w.builder.WriteString(content + "\r\n")
+}
+
+func (w *icalWriter) text(name string, value string) {
+	w.line(name + ":" + icalTextEscaper.Replace(value))
+}
+
+// localTime writes a date-time property as local time of the event timezone,
+// so recurring events keep their time of day across daylight saving changes.
+// The timezone is described by its VTIMEZONE, unknown ones fall back to UTC.
+func (w *icalWriter) localTime(name string, timezone string, value time.Time) {
+	location, err := utils.LoadTimezone(timezone)
+	if err != nil {
+		w.line(name + ":" + value.UTC().Format(icalDateTimeLayout))
+
+		return
+	}
+
+	w.line(name + ";TZID=" + location.String() + ":" + value.In(location).Format(icalLocalLayout))
+}
+
+func (w *icalWriter) event(event icalEvent, stamp time.Time) {
+	w.line("BEGIN:VEVENT")
+	w.line("UID:" + event.PublicID + "@" + icalDomain)
+	w.line("DTSTAMP:" + stamp.UTC().Format(icalDateTimeLayout))
+	w.line("CREATED:" + event.CreatedAt.UTC().Format(icalDateTimeLayout))
+
+	if event.RecurrenceID != nil {
+		w.localTime("RECURRENCE-ID", event.Timezone, *event.RecurrenceID)
+	}
+
+	w.localTime("DTSTART", event.Timezone, event.StartsAt)
+	if event.EndsAt != nil {
+		w.localTime("DTEND", event.Timezone, *event.EndsAt)
+	}
+
+	if event.RecurrenceRule != nil && event.RecurrenceID == nil {
+		w.line("RRULE:" + *event.RecurrenceRule)
+
+		if len(event.ExceptionDates) > 0 {
+			// EXDATE has to match the local start time of the cancelled occurrences
+			location, err := utils.LoadTimezone(event.Timezone)
+			property, layout := "EXDATE", icalDateTimeLayout
+			if err == nil {
+				property, layout = "EXDATE;TZID="+location.String(), icalLocalLayout
+			} else {
+				location = time.UTC
+			}
+
+			start := event.StartsAt.In(location)
+			exdates := make([]string, 0, len(event.ExceptionDates))
+			for _, value := range event.ExceptionDates {
+				if len(value) > len(isoDateLayout) {
+					value = value[:len(isoDateLayout)]
+				}
+
+				date, err := time.Parse(isoDateLayout, value)
+				if err != nil {
+					continue
+				}
+				exdates = append(exdates, utils.OnDay(start, date).Format(layout))
+			}
+			w.line(property + ":" + strings.Join(exdates, ","))
+		}
+	}
+
+	// subscribed calendars drop or strike through cancelled events
+	if event.Status == models.EventStatusCancelled {
+		w.line("STATUS:CANCELLED")
+	}
+
+	w.text("SUMMARY", event.Name)
+	w.text("LOCATION", event.Location)
+	w.text("DESCRIPTION", utils.StripHTML(event.Description))
+	w.line("END:VEVENT")
+}
+
+func renderCalendar(name string, events []icalEvent) string {
+	w := &icalWriter{}
+	stamp := time.Now()
+
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:-//SportujSpolu//SportujSpolu API//CS")
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	w.text("X-WR-CALNAME", name)
+
+	w.timezones(events, stamp)
+
+	for _, event := range events {
+		w.event(event, stamp)
+	}
+
+	w.line("END:VCALENDAR")
+
+	return w.builder.String()
+}
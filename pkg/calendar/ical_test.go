@@ -0,0 +1,69 @@
+package calendar
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderCalendarTimezones(t *testing.T) {
+	rule := "FREQ=WEEKLY"
+	start := time.Date(2024, time.March, 20, 17, 0, 0, 0, time.UTC)
+	events := []icalEvent{
+		{PublicID: "a", Name: "Prague", StartsAt: start, Timezone: "Europe/Prague", RecurrenceRule: &rule, ExceptionDates: []string{"2024-03-27"}},
+		{PublicID: "b", Name: "New York", StartsAt: start, Timezone: "America/New_York"},
+		{PublicID: "c", Name: "Tokyo", StartsAt: start, Timezone: "Asia/Tokyo"},
+	}
+
+	calendar := renderCalendar("test", events)
+
+	for _, match := range regexp.MustCompile(`;TZID=([^:;]+)`).FindAllStringSubmatch(calendar, -1) {
+		if !strings.Contains(calendar, "\r\nTZID:"+match[1]+"\r\n") {
+			t.Errorf("TZID %s is used without a VTIMEZONE", match[1])
+		}
+	}
+
+	if count := strings.Count(calendar, "BEGIN:VTIMEZONE"); count != 3 {
+		t.Errorf("calendar has %d VTIMEZONE components, want 3", count)
+	}
+
+	for _, line := range []string{
+		"DTSTART;TZID=Europe/Prague:20240320T180000",
+		"EXDATE;TZID=Europe/Prague:20240327T180000",
+		"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU",
+		"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+		"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU",
+		"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0200",
+		"TZOFFSETTO:-0400",
+		"TZOFFSETTO:+0900",
+		"DTSTART:20240331T020000",
+	} {
+		if !strings.Contains(calendar, "\r\n"+line+"\r\n") {
+			t.Errorf("calendar does not contain %s", line)
+		}
+	}
+
+	// the VTIMEZONE components come before the events that use them
+	if strings.Index(calendar, "BEGIN:VTIMEZONE") > strings.Index(calendar, "BEGIN:VEVENT") {
+		t.Error("VTIMEZONE is written after the events")
+	}
+}
+
+func TestRenderCalendarUnknownTimezone(t *testing.T) {
+	events := []icalEvent{
+		{PublicID: "a", Name: "Unknown", StartsAt: time.Date(2024, time.March, 20, 17, 0, 0, 0, time.UTC), Timezone: "Mars/Olympus"},
+	}
+
+	calendar := renderCalendar("test", events)
+
+	if strings.Contains(calendar, "TZID") {
+		t.Error("unknown timezone is written as TZID")
+	}
+
+	if !strings.Contains(calendar, "\r\nDTSTART:20240320T170000Z\r\n") {
+		t.Error("unknown timezone does not fall back to UTC")
+	}
+}
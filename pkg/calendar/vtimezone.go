@@ -0,0 +1,220 @@
+package calendar
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/globus303/sportujspolu/utils"
+)
+
+var icalWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// zoneTransition is a change of the UTC offset of a timezone.
+type zoneTransition struct {
+	at         time.Time
+	offsetFrom int
+	offsetTo   int
+	name       string
+	dst        bool
+}
+
+// localStart is the wall clock time of the transition before it happens, the
+// DTSTART of its VTIMEZONE observance.
+func (t zoneTransition) localStart() time.Time {
+	return t.at.UTC().Add(time.Duration(t.offsetFrom) * time.Second)
+}
+
+// yearlyRule returns the RRULE repeating the transition on the same weekday
+// of the month, e.g. the last Sunday of March.
+func (t zoneTransition) yearlyRule() (time.Month, int, time.Weekday) {
+	start := t.localStart()
+	daysInMonth := time.Date(start.Year(), start.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	nth := (start.Day()-1)/7 + 1
+	if start.Day()+7 > daysInMonth {
+		nth = -1
+	}
+
+	return start.Month(), nth, start.Weekday()
+}
+
+// matchesRule reports whether the transition happens on the day the yearly
+// rule of previous gives for its year, at the same time and offsets.
+func (t zoneTransition) matchesRule(previous zoneTransition) bool {
+	month, nth, weekday := previous.yearlyRule()
+	start, before := t.localStart(), previous.localStart()
+
+	if t.offsetFrom != previous.offsetFrom || t.offsetTo != previous.offsetTo || t.name != previous.name {
+		return false
+	}
+
+	if start.Month() != month || start.Weekday() != weekday || start.Hour() != before.Hour() || start.Minute() != before.Minute() {
+		return false
+	}
+
+	daysInMonth := time.Date(start.Year(), start.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if nth < 0 {
+		return start.Day()+7 > daysInMonth
+	}
+
+	return (start.Day()-1)/7+1 == nth
+}
+
+// zoneTransitions walks the offset changes of the location in [from, to).
+func zoneTransitions(location *time.Location, from, to time.Time) []zoneTransition {
+	transitions := []zoneTransition{}
+
+	current := from.In(location)
+	for {
+		_, end := current.ZoneBounds()
+		if end.IsZero() || !end.Before(to) {
+			return transitions
+		}
+
+		next := end.In(location)
+		_, offsetFrom := current.Zone()
+		name, offsetTo := next.Zone()
+		if offsetFrom != offsetTo {
+			transitions = append(transitions, zoneTransition{at: end, offsetFrom: offsetFrom, offsetTo: offsetTo, name: name, dst: next.IsDST()})
+		}
+
+		current = next
+	}
+}
+
+func formatUTCOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	value := sign + twoDigits(offset/3600) + twoDigits(offset%3600/60)
+	if offset%60 != 0 {
+		value += twoDigits(offset % 60)
+	}
+
+	return value
+}
+
+func twoDigits(value int) string {
+	if value < 10 {
+		return "0" + strconv.Itoa(value)
+	}
+
+	return strconv.Itoa(value)
+}
+
+func (w *icalWriter) observance(start time.Time, offsetFrom, offsetTo int, name string, dst bool, rule string) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+
+	w.line("BEGIN:" + kind)
+	w.line("DTSTART:" + start.Format(icalLocalLayout))
+	if rule != "" {
+		w.line("RRULE:" + rule)
+	}
+	w.line("TZOFFSETFROM:" + formatUTCOffset(offsetFrom))
+	w.line("TZOFFSETTO:" + formatUTCOffset(offsetTo))
+	w.text("TZNAME", name)
+	w.line("END:" + kind)
+}
+
+// timezone writes the VTIMEZONE of the location from the tzdata transitions
+// between the years. The transitions of the last year repeat yearly when the
+// year after follows the same rule, so endless series stay covered.
+func (w *icalWriter) timezone(location *time.Location, firstYear, lastYear int) {
+	from := time.Date(firstYear, time.January, 1, 0, 0, 0, 0, time.UTC)
+	lastFrom := time.Date(lastYear, time.January, 1, 0, 0, 0, 0, time.UTC)
+	transitions := zoneTransitions(location, from, time.Date(lastYear+2, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	explicit, last, after := []zoneTransition{}, []zoneTransition{}, []zoneTransition{}
+	for _, transition := range transitions {
+		switch {
+		case transition.at.Before(lastFrom):
+			explicit = append(explicit, transition)
+		case transition.at.Year() == lastYear:
+			last = append(last, transition)
+		default:
+			after = append(after, transition)
+		}
+	}
+
+	repeats := len(last) > 0 && len(last) == len(after)
+	for i := range after {
+		repeats = repeats && after[i].matchesRule(last[i])
+	}
+
+	if !repeats {
+		explicit = append(explicit, last...)
+		explicit = append(explicit, after...)
+		last = nil
+	}
+
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + location.String())
+
+	// the offset in effect at the start of the range, before any transition
+	initial := from.In(location)
+	name, offset := initial.Zone()
+	w.observance(from.Add(time.Duration(offset)*time.Second), offset, offset, name, initial.IsDST(), "")
+
+	for _, transition := range explicit {
+		w.observance(transition.localStart(), transition.offsetFrom, transition.offsetTo, transition.name, transition.dst, "")
+	}
+
+	for _, transition := range last {
+		month, nth, weekday := transition.yearlyRule()
+		rule := "FREQ=YEARLY;BYMONTH=" + strconv.Itoa(int(month)) + ";BYDAY=" + strconv.Itoa(nth) + icalWeekdays[weekday]
+		w.observance(transition.localStart(), transition.offsetFrom, transition.offsetTo, transition.name, transition.dst, rule)
+	}
+
+	w.line("END:VTIMEZONE")
+}
+
+// timezones writes a VTIMEZONE for every timezone the events use, covering
+// the years from the first event until the year after the last one.
+func (w *icalWriter) timezones(events []icalEvent, now time.Time) {
+	locations := map[string]*time.Location{}
+	firstYears := map[string]int{}
+	lastYears := map[string]int{}
+	for _, event := range events {
+		location, err := utils.LoadTimezone(event.Timezone)
+		if err != nil {
+			continue
+		}
+		locations[location.String()] = location
+
+		year := event.StartsAt.Year()
+		if event.RecurrenceID != nil && event.RecurrenceID.Year() < year {
+			year = event.RecurrenceID.Year()
+		}
+		if first, ok := firstYears[location.String()]; !ok || year < first {
+			firstYears[location.String()] = year
+		}
+
+		last := now.Year()
+		if event.StartsAt.Year() > last {
+			last = event.StartsAt.Year()
+		}
+		if event.EndsAt != nil && event.EndsAt.Year() > last {
+			last = event.EndsAt.Year()
+		}
+		if last > lastYears[location.String()] {
+			lastYears[location.String()] = last
+		}
+	}
+
+	names := make([]string, 0, len(firstYears))
+	for name := range firstYears {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		w.timezone(locations[name], firstYears[name]-1, lastYears[name]+1)
+	}
+}
@@ -0,0 +1,491 @@
+package comments
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/pkg/roles"
+	"github.com/globus303/sportujspolu/utils"
+	"github.com/lib/pq"
+)
+
+const (
+	maxCommentLength = 2000
+	maxCommentsLimit = 100
+	commentColumns   = "event_comments.public_id, event_comments.event_id, event_comments.parent_id, event_comments.user_id, users.name, " +
+		"event_comments.text, event_comments.hidden, event_comments.pinned, event_comments.created_at, event_comments.edited_at"
+)
+
+func getColumnsForComment(comment *models.Comment) []interface{} {
+	return []interface{}{&comment.ID, &comment.EventID, &comment.ParentID, &comment.AuthorID, &comment.AuthorName,
+		&comment.Text, &comment.Hidden, &comment.Pinned, &comment.CreatedAt, &comment.EditedAt}
+}
+
+type CommentsService struct {
+	db *sql.DB
+}
+
+func NewCommentsService(db *sql.DB) *CommentsService {
+	return &CommentsService{db}
+}
+
+type commentEvent struct {
+	status string
+	// role of the current user in the event, empty for everyone else
+	role string
+}
+
+func (s *CommentsService) getEvent(eventId string, userID string) (*commentEvent, error) {
+	var event commentEvent
+	if err := s.db.QueryRow("SELECT status FROM events WHERE public_id = $1 AND deleted_at IS NULL", eventId).Scan(&event.status); err != nil {
+		return nil, err
+	}
+
+	if userID == "" {
+		return &event, nil
+	}
+
+	var err error
+	if event.role, err = roles.GetUserRole(s.db, eventId, userID); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// visible hides drafts and their comments from everyone but the organizers.
+func (e *commentEvent) visible() bool {
+	return e.status != models.EventStatusDraft || e.role != ""
+}
+
+func (s *CommentsService) getComment(eventId string, commentId string) (*models.Comment, error) {
+	var comment models.Comment
+	query := "SELECT " + commentColumns + " FROM event_comments JOIN users ON users.id = event_comments.user_id AND users.deleted_at IS NULL WHERE event_comments.event_id = $1 AND event_comments.public_id = $2"
+	if err := s.db.QueryRow(query, eventId, commentId).Scan(getColumnsForComment(&comment)...); err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+func queryComments(rows *sql.Rows) ([]models.Comment, error) {
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var comment models.Comment
+		if err := rows.Scan(getColumnsForComment(&comment)...); err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+func validateCommentText(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+
+	return text, text != "" && utf8.RuneCountInString(text) <= maxCommentLength
+}
+
+// @Summary Get event comments
+// @Description Lists top level comments of the event with their replies, pinned comments first. Hidden comments are returned only to the event organizers and moderators.
+// @Tags comments
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of top level comments per page, at most 100" default(20)
+// @Success 200 {array} models.Comment
+// @Header 200 {integer} X-Total-Count "Number of top level comments"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /events/{eventId}/comments [get]
+func (s *CommentsService) GetComments(c *gin.Context) {
+	eventId := c.Param("eventId")
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid page parameter"))
+
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > maxCommentsLimit {
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid limit parameter"))
+
+		return
+	}
+
+	// the route is public, a token only unlocks the organizers' view
+	userID, _ := utils.TokenValid(c)
+
+	event, err := s.getEvent(eventId, userID)
+	if err != nil || !event.visible() {
+		log.Println("(GetComments) getEvent", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+		return
+	}
+
+	visibility := ""
+	if !roles.CanModerate(event.role) {
+		visibility = " AND NOT event_comments.hidden"
+	}
+
+	// the total and the page share the same filter, comments of deleted users
+	// are left out of both
+	from := " FROM event_comments JOIN users ON users.id = event_comments.user_id AND users.deleted_at IS NULL " +
+		"WHERE event_comments.event_id = $1 AND event_comments.parent_id IS NULL" + visibility
+
+	var total int
+	err = s.db.QueryRow("SELECT COUNT(*)"+from, eventId).Scan(&total)
+	if err != nil {
+		log.Println("(GetComments) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving comments"))
+
+		return
+	}
+
+	query := "SELECT " + commentColumns + from +
+		" ORDER BY event_comments.pinned DESC, event_comments.created_at, event_comments.id LIMIT $2 OFFSET $3"
+	rows, err := s.db.Query(query, eventId, limit, (page-1)*limit)
+	if err != nil {
+		log.Println("(GetComments) db.Query", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving comments"))
+
+		return
+	}
+
+	comments, err := queryComments(rows)
+	if err != nil {
+		log.Println("(GetComments) queryComments", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error processing comments"))
+
+		return
+	}
+
+	parentIDs := make([]string, len(comments))
+	indexes := map[string]int{}
+	for i, comment := range comments {
+		parentIDs[i] = comment.ID
+		indexes[comment.ID] = i
+	}
+
+	query = "SELECT " + commentColumns + " FROM event_comments JOIN users ON users.id = event_comments.user_id AND users.deleted_at IS NULL " +
+		"WHERE event_comments.parent_id = ANY($1)" + visibility + " ORDER BY event_comments.created_at, event_comments.id"
+	rows, err = s.db.Query(query, pq.Array(parentIDs))
+	if err != nil {
+		log.Println("(GetComments) db.Query", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving comments"))
+
+		return
+	}
+
+	replies, err := queryComments(rows)
+	if err != nil {
+		log.Println("(GetComments) queryComments", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error processing comments"))
+
+		return
+	}
+
+	for _, reply := range replies {
+		parent := &comments[indexes[*reply.ParentID]]
+		parent.Replies = append(parent.Replies, reply)
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, comments)
+}
+
+// @Summary Create a comment
+// @Description Adds a comment to the event. Set parentId to reply to a top level comment, replies cannot be nested further and hidden comments cannot be replied to.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param comment body models.CommentInput true "Comment"
+// @Success 200 {object} models.Comment
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/comments [post]
+func (s *CommentsService) CreateComment(c *gin.Context) {
+	eventId := c.Param("eventId")
+	userID := c.GetString(constants.UserID_key)
+
+	var input models.CommentInput
+	if err := c.BindJSON(&input); err != nil {
+		log.Println("(CreateComment) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid request body"))
+
+		return
+	}
+
+	text, ok := validateCommentText(input.Text)
+	if !ok {
+		c.JSON(http.StatusBadRequest, utils.GetError("text must have between 1 and 2000 characters"))
+
+		return
+	}
+
+	event, err := s.getEvent(eventId, userID)
+	if err != nil || !event.visible() {
+		log.Println("(CreateComment) getEvent", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+		return
+	}
+
+	if input.ParentID != nil {
+		parent, err := s.getComment(eventId, *input.ParentID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, utils.GetError("Parent comment not found"))
+
+			return
+		}
+		if err != nil {
+			log.Println("(CreateComment) getComment", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error creating comment"))
+
+			return
+		}
+
+		// a hidden comment is gone for everybody but the moderators, so it
+		// cannot gather new replies
+		if parent.Hidden {
+			c.JSON(http.StatusNotFound, utils.GetError("Parent comment not found"))
+
+			return
+		}
+
+		if parent.ParentID != nil {
+			c.JSON(http.StatusBadRequest, utils.GetError("Replies cannot be nested"))
+
+			return
+		}
+	}
+
+	commentId := utils.GenerateUUID()
+	query := "INSERT INTO event_comments (public_id, event_id, user_id, parent_id, text) VALUES ($1, $2, $3, $4, $5)"
+	if _, err := s.db.Exec(query, commentId, eventId, userID, input.ParentID, text); err != nil {
+		log.Println("(CreateComment) db.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error creating comment"))
+
+		return
+	}
+
+	comment, err := s.getComment(eventId, commentId)
+	if err != nil {
+		log.Println("(CreateComment) getComment", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error reading created comment"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// @Summary Edit a comment
+// @Description Changes the text of the comment, available only to its author
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param commentId path string true "Comment ID" example(pwnrxtbi9z0v)
+// @Param comment body models.CommentUpdateInput true "New text"
+// @Success 200 {object} models.Comment
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/comments/{commentId} [put]
+func (s *CommentsService) UpdateComment(c *gin.Context) {
+	eventId := c.Param("eventId")
+	commentId := c.Param("commentId")
+	userID := c.GetString(constants.UserID_key)
+
+	var input models.CommentUpdateInput
+	if err := c.BindJSON(&input); err != nil {
+		log.Println("(UpdateComment) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid request body"))
+
+		return
+	}
+
+	text, ok := validateCommentText(input.Text)
+	if !ok {
+		c.JSON(http.StatusBadRequest, utils.GetError("text must have between 1 and 2000 characters"))
+
+		return
+	}
+
+	comment, err := s.getComment(eventId, commentId)
+	if err != nil {
+		log.Println("(UpdateComment) getComment", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Comment not found"))
+
+		return
+	}
+
+	if comment.AuthorID != userID {
+		c.JSON(http.StatusForbidden, utils.GetError("You are not the author of this comment"))
+
+		return
+	}
+
+	editedAt := time.Now()
+	if _, err := s.db.Exec("UPDATE event_comments SET text = $1, edited_at = $2 WHERE public_id = $3", text, editedAt, commentId); err != nil {
+		log.Println("(UpdateComment) db.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error updating comment"))
+
+		return
+	}
+
+	comment.Text = text
+	comment.EditedAt = &editedAt
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// @Summary Delete a comment
+// @Description Deletes the comment together with its replies. Available to the comment author and the event organizers and moderators.
+// @Tags comments
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param commentId path string true "Comment ID" example(pwnrxtbi9z0v)
+// @Success 200
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/comments/{commentId} [delete]
+func (s *CommentsService) DeleteComment(c *gin.Context) {
+	eventId := c.Param("eventId")
+	commentId := c.Param("commentId")
+	userID := c.GetString(constants.UserID_key)
+
+	comment, err := s.getComment(eventId, commentId)
+	if err != nil {
+		log.Println("(DeleteComment) getComment", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Comment not found"))
+
+		return
+	}
+
+	if comment.AuthorID != userID {
+		event, err := s.getEvent(eventId, userID)
+		if err != nil {
+			log.Println("(DeleteComment) getEvent", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error deleting comment"))
+
+			return
+		}
+
+		if !roles.CanModerate(event.role) {
+			c.JSON(http.StatusForbidden, utils.GetError("You cannot delete this comment"))
+
+			return
+		}
+	}
+
+	if _, err := s.db.Exec("DELETE FROM event_comments WHERE public_id = $1", commentId); err != nil {
+		log.Println("(DeleteComment) db.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error deleting comment"))
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Moderate a comment
+// @Description Hides or pins the comment, available to the event organizers and moderators. Only top level comments can be pinned.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param commentId path string true "Comment ID" example(pwnrxtbi9z0v)
+// @Param moderation body models.CommentModerationInput true "Flags to change"
+// @Success 200 {object} models.Comment
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/comments/{commentId}/moderation [put]
+func (s *CommentsService) ModerateComment(c *gin.Context) {
+	eventId := c.Param("eventId")
+	commentId := c.Param("commentId")
+	userID := c.GetString(constants.UserID_key)
+
+	var input models.CommentModerationInput
+	if err := c.BindJSON(&input); err != nil {
+		log.Println("(ModerateComment) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid request body"))
+
+		return
+	}
+
+	event, err := s.getEvent(eventId, userID)
+	if err != nil {
+		log.Println("(ModerateComment) getEvent", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+		return
+	}
+
+	if !roles.CanModerate(event.role) {
+		c.JSON(http.StatusForbidden, utils.GetError("You are not a moderator of this event"))
+
+		return
+	}
+
+	comment, err := s.getComment(eventId, commentId)
+	if err != nil {
+		log.Println("(ModerateComment) getComment", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Comment not found"))
+
+		return
+	}
+
+	if input.Hidden != nil {
+		comment.Hidden = *input.Hidden
+	}
+
+	if input.Pinned != nil {
+		if *input.Pinned && comment.ParentID != nil {
+			c.JSON(http.StatusBadRequest, utils.GetError("Only top level comments can be pinned"))
+
+			return
+		}
+
+		comment.Pinned = *input.Pinned
+	}
+
+	if _, err := s.db.Exec("UPDATE event_comments SET hidden = $1, pinned = $2 WHERE public_id = $3", comment.Hidden, comment.Pinned, commentId); err != nil {
+		log.Println("(ModerateComment) db.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error moderating comment"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
@@ -2,29 +2,50 @@ package events
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/globus303/sportujspolu/constants"
 	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/pkg/roles"
+	"github.com/globus303/sportujspolu/pkg/storage"
+	"github.com/globus303/sportujspolu/pkg/user"
+	"github.com/globus303/sportujspolu/pkg/venues"
 	"github.com/globus303/sportujspolu/utils"
+	"github.com/lib/pq"
 )
 
-const columns = "name, sport, date, location, price, description, level, public_id, created_at, owner_id"
+const columns = "id, name, sport, starts_at, ends_at, timezone, location, venue_id, latitude, longitude, price, description, level, public_id, created_at, owner_id, capacity, recurrence_rule, recurrence_exdates, status, cancellation_reason, " +
+	"GREATEST(capacity - (SELECT COUNT(*) FROM event_participants WHERE event_participants.event_id = events.public_id), 0) AS spots_left, " +
+	"(SELECT image_url FROM event_images WHERE event_images.event_id = events.public_id AND kind = 'cover') AS cover_image_url, " +
+	"(SELECT thumbnail_url FROM event_images WHERE event_images.event_id = events.public_id AND kind = 'cover') AS cover_thumbnail_url, " +
+	tagsColumn + " AS tags"
 
 func getColumnForEvent(event *models.EventWithOwner) []interface{} {
-	return []interface{}{&event.Name, &event.Sport, &event.Date, &event.Location, &event.Price, &event.Description, &event.Level, &event.Public_ID, &event.Created_At, &event.Owner_ID}
+	return []interface{}{&event.ID, &event.Name, &event.Sport, &event.StartsAt, &event.EndsAt, &event.Timezone, &event.Location, &event.VenueID, &event.Latitude, &event.Longitude, &event.Price, &event.Description, &event.Level, &event.Public_ID, &event.Created_At, &event.Owner_ID, &event.Capacity, &event.RecurrenceRule, pq.Array(&event.ExceptionDates), &event.Status, &event.CancellationReason, &event.SpotsLeft, &event.CoverImageURL, &event.CoverThumbnailURL, pq.Array(&event.Tags)}
+}
+
+// presentEvent fills in the values derived for the response.
+func presentEvent(event *models.Event) {
+	localizeEvent(event)
+	event.DescriptionText = utils.StripHTML(event.Description)
+	if event.Tags == nil {
+		event.Tags = []string{}
+	}
 }
 
 type EventsService struct {
-	db *sql.DB
+	db      *sql.DB
+	storage storage.Storage
 }
 
-func NewEventsService(db *sql.DB) *EventsService {
-	return &EventsService{db}
+func NewEventsService(db *sql.DB, storage storage.Storage) *EventsService {
+	return &EventsService{db, storage}
 }
 
 func (s *EventsService) includeOwner(event *models.EventWithOwner, c *gin.Context) error {
@@ -39,49 +60,134 @@ func (s *EventsService) includeOwner(event *models.EventWithOwner, c *gin.Contex
 	ownerID := event.Owner_ID
 
 	var owner models.PublicUser
-	err := s.db.QueryRow("SELECT id, name, email, rating FROM users WHERE id = $1", ownerID).
-		Scan(&owner.ID, &owner.Name, &owner.Email, &owner.Rating)
+	query := "SELECT id, name, email, rating, " + user.AttendanceColumns("users.id") + " FROM users WHERE id = $1 AND deleted_at IS NULL"
+	err := s.db.QueryRow(query, ownerID).
+		Scan(&owner.ID, &owner.Name, &owner.Email, &owner.Rating, &owner.Attended, &owner.NoShows)
 
 	if err != nil {
 		return err
 	}
 
+	owner.Reliability = user.Reliability(owner.Attended, owner.NoShows)
+
 	event.Owner = &owner
 
 	return nil
 }
 
+func (s *EventsService) sportExists(sport string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM sports WHERE value = $1)", sport).Scan(&exists)
+
+	return exists, err
+}
+
+func (s *EventsService) levelExists(level string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM levels WHERE value = $1)", level).Scan(&exists)
+
+	return exists, err
+}
+
 // @Summary Get all events
-// @Description Retrieve all events from the database
+// @Description Retrieve all events from the database. All filters are optional and combined with AND.
 // @Tags events
 // @Produce json
-// @Param page query int false "Page number" default(1)
+// @Param page query int false "Page number, ignored in cursor mode" default(1)
 // @Param limit query int false "Number of events per page" default(12)
+// @Param cursor query string false "Opaque cursor from nextCursor or the Link header. Send it empty to start cursor pagination, which stays stable while new events are created."
+// @Param withTotal query bool false "Count all matching events, returned in X-Total-Count and in the envelope"
+// @Param envelope query bool false "Wrap the events in an object with data, total and nextCursor"
 // @Param includes query string false "Include additional details" Enums(owner)
-// @Success 200 {array} models.EventWithOwner
+// @Param q query string false "Full-text search over name, description, location and sport, ignores diacritics. Results are ordered by relevance unless sort is set." example(beh)
+// @Param sport query string false "Sport, one of the values from /references/sports" example(basketball)
+// @Param level query string false "Level, one of the values from /references/levels" example(beginner)
+// @Param tags query string false "Comma separated tags, events have to carry all of them" example(outdoor,family friendly)
+// @Param location query string false "Location, case-insensitive substring match" example(Brno)
+// @Param venue query string false "Venue ID, only events at the venue" example(v7k2m9xq4pza)
+// @Param dateFrom query string false "Earliest event date in the event timezone (inclusive), YYYY-MM-DD" example(2024-01-01)
+// @Param dateTo query string false "Latest event date in the event timezone (inclusive), YYYY-MM-DD" example(2024-12-31)
+// @Param priceMin query int false "Minimum price (inclusive)" minimum(0)
+// @Param priceMax query int false "Maximum price (inclusive)" minimum(0)
+// @Param free query bool false "Only events with price 0"
+// @Param lat query number false "Latitude of the search origin, requires lng. Each event then includes distanceKm." example(49.1951)
+// @Param lng query number false "Longitude of the search origin, requires lat" example(16.6068)
+// @Param radiusKm query number false "Only events within this distance from lat/lng" example(10)
+// @Param status query string false "Event status, drafts are never listed" Enums(published, cancelled, completed)
+// @Param sort query string false "Sort order, a leading minus means descending, date sorts by the start time. Defaults to relevance with q, date with expand and -createdAt otherwise. distance requires lat and lng." Enums(date, -date, price, -price, createdAt, -createdAt, distance)
+// @Param includePast query bool false "Include events whose date already passed" default(false)
+// @Param expand query bool false "Expand recurring events into single occurrences ordered by date, requires dateFrom and dateTo at most 366 days apart"
+// @Success 200 {array} models.EventWithOwner "Plain array, or models.EventPage when envelope=true"
+// @Header 200 {string} Link "RFC 8288 links to the first, previous, next and last page"
+// @Header 200 {integer} X-Total-Count "Number of matching events, only with withTotal=true"
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
 // @Router /events [get]
 func (s *EventsService) GetAllEvents(c *gin.Context) {
-	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if err != nil || page < 1 {
-		log.Println("(GetAllEvents)", err)
-		c.JSON(http.StatusBadRequest, utils.GetError("Invalid page parameter"))
+	s.listEvents(c, eventScope{})
+}
+
+// listEvents writes a filtered and paginated listing of the events in scope.
+func (s *EventsService) listEvents(c *gin.Context, scope eventScope) {
+	paging, err := parsePagination(c)
+	if err != nil {
+		log.Println("(listEvents) parsePagination", err)
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
+	}
+
+	filters, err := parseEventFilters(c, scope)
+	if err != nil {
+		log.Println("(listEvents) parseEventFilters", err)
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
 
 		return
 	}
 
-	limit, err := strconv.Atoi(c.DefaultQuery("limit", "12"))
-	if err != nil || limit < 1 {
-		log.Println("(GetAllEvents)", err)
-		c.JSON(http.StatusBadRequest, utils.GetError("Invalid limit parameter"))
+	if paging.CursorMode && (filters.Sort == sortRelevance || filters.Expand) {
+		c.JSON(http.StatusBadRequest, utils.GetError("cursor requires an explicit sort when searching and cannot be combined with expand"))
 
 		return
 	}
 
-	offset := (page - 1) * limit
-	res, err := s.db.Query("SELECT "+columns+" FROM events ORDER BY created_at DESC LIMIT $1 OFFSET $2", limit, offset)
+	if paging.Cursor != nil && paging.Cursor.Sort != filters.Sort {
+		c.JSON(http.StatusBadRequest, utils.GetError("cursor was created for a different sort"))
+
+		return
+	}
+
+	q, ok := s.listQuery(c, scope, filters)
+	if !ok {
+		return
+	}
+
+	var total *int
+	if paging.WithTotal && !filters.Expand {
+		var count int
+		countQuery, countArgs := q.buildCount()
+		if err := s.db.QueryRow(countQuery, countArgs...).Scan(&count); err != nil {
+			log.Println("(listEvents) db.QueryRow", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving events"))
+
+			return
+		}
+		total = &count
+	}
+
+	// occurrences only exist after expansion, so expanded listings are
+	// paginated in memory over the bounded date window
+	if !filters.Expand {
+		paging.apply(q, filters.Sort)
+	}
+
+	query, args := q.build()
+	res, err := s.db.Query(query, args...)
 	if err != nil {
-		log.Println("(GetAllEvents) db.Query", err)
+		log.Println("(listEvents) db.Query", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving events"))
+
+		return
 	}
 
 	defer res.Close()
@@ -89,22 +195,128 @@ func (s *EventsService) GetAllEvents(c *gin.Context) {
 	events := []models.EventWithOwner{}
 	for res.Next() {
 		var event models.EventWithOwner
-		err := res.Scan(getColumnForEvent(&event)...)
+		err := res.Scan(append(getColumnForEvent(&event), &event.DistanceKm)...)
 		if err != nil {
-			log.Println("(GetAllEvents) res.Scan", err)
+			log.Println("(listEvents) res.Scan", err)
 		}
+		presentEvent(&event.Event)
 		events = append(events, event)
 	}
 
+	if filters.Expand {
+		events, err = s.expandOccurrences(events, *filters.DateFrom, *filters.DateTo)
+		if err != nil {
+			log.Println("(listEvents) expandOccurrences", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving events"))
+
+			return
+		}
+
+		count := len(events)
+		total = &count
+		events = paginate(events, paging.Page, paging.Limit)
+	}
+
+	hasNext := len(events) == paging.Limit
+	if total != nil {
+		hasNext = paging.Page*paging.Limit < *total
+	}
+
+	var nextCursor *string
+	if paging.CursorMode {
+		hasNext = len(events) > paging.Limit
+		if hasNext {
+			events = events[:paging.Limit]
+			cursor := encodeCursor(events[len(events)-1], filters.Sort)
+			nextCursor = &cursor
+		}
+	}
+
 	for i := range events {
 		if err := s.includeOwner(&events[i], c); err != nil {
-			log.Println("(GetAllEvents) includeOwner", err)
+			log.Println("(listEvents) includeOwner", err)
 		}
 	}
 
+	cursorValue := ""
+	if nextCursor != nil {
+		cursorValue = *nextCursor
+	}
+	setLinkHeader(c, paging, hasNext, cursorValue, total)
+
+	if total != nil {
+		c.Header("X-Total-Count", strconv.Itoa(*total))
+	}
+
+	if paging.Envelope {
+		c.JSON(http.StatusOK, models.EventPage{Data: events, Total: total, NextCursor: nextCursor})
+
+		return
+	}
+
 	c.JSON(http.StatusOK, events)
 }
 
+// listQuery checks the sport and level filters against the references and
+// builds the sorted query of a listing, writing the error response when it
+// fails.
+func (s *EventsService) listQuery(c *gin.Context, scope eventScope, filters *eventFilters) (*eventQuery, bool) {
+	if filters.Sport != "" {
+		exists, err := s.sportExists(filters.Sport)
+		if err != nil {
+			log.Println("(listQuery) sportExists", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving events"))
+
+			return nil, false
+		}
+
+		if !exists {
+			c.JSON(http.StatusBadRequest, utils.GetError("Invalid sport parameter"))
+
+			return nil, false
+		}
+	}
+
+	if filters.Level != "" {
+		exists, err := s.levelExists(filters.Level)
+		if err != nil {
+			log.Println("(listQuery) levelExists", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving events"))
+
+			return nil, false
+		}
+
+		if !exists {
+			c.JSON(http.StatusBadRequest, utils.GetError("Invalid level parameter"))
+
+			return nil, false
+		}
+	}
+
+	q := newEventQuery()
+	if scope.condition != nil {
+		q.where(scope.condition(q))
+	}
+	filters.apply(q)
+	applySort(q, filters.Sort)
+
+	return q, true
+}
+
+func paginate(events []models.EventWithOwner, page int, limit int) []models.EventWithOwner {
+	offset := (page - 1) * limit
+	if offset >= len(events) {
+		return []models.EventWithOwner{}
+	}
+
+	end := offset + limit
+	if end > len(events) {
+		end = len(events)
+	}
+
+	return events[offset:end]
+}
+
 // @Summary Get a single event
 // @Description Retrieves a single event from the database
 // @Tags events
@@ -120,7 +332,7 @@ func (s *EventsService) GetSingleEvent(c *gin.Context) {
 	eventId := c.Param("eventId")
 
 	var event models.EventWithOwner
-	query := "SELECT " + columns + " FROM events WHERE public_id = $1"
+	query := "SELECT " + columns + " FROM events WHERE public_id = $1 AND deleted_at IS NULL"
 	err := s.db.QueryRow(query, eventId).Scan(getColumnForEvent(&event)...)
 	if err != nil {
 		log.Println("(GetSingleEvent) db.Exec", err)
@@ -128,88 +340,188 @@ func (s *EventsService) GetSingleEvent(c *gin.Context) {
 
 		return
 	}
+	presentEvent(&event.Event)
+
+	// drafts are visible only to their organizers, the route itself is public
+	if event.Status == models.EventStatusDraft {
+		userID, err := utils.TokenValid(c)
+		if err != nil {
+			c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+			return
+		}
+
+		role, err := roles.GetUserRole(s.db, eventId, userID)
+		if err != nil || role == "" {
+			c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+			return
+		}
+	}
+
+	if event.Gallery, err = s.getGallery(eventId); err != nil {
+		log.Println("(GetSingleEvent) getGallery", err)
+	}
 
 	if err := s.includeOwner(&event, c); err != nil {
 		log.Println("(GetSingleEvent) includeOwner", err)
 	}
 
+	if event.VenueID != nil {
+		if event.Venue, err = venues.GetVenue(s.db, *event.VenueID); err != nil {
+			log.Println("(GetSingleEvent) GetVenue", err)
+		}
+	}
+
 	c.JSON(http.StatusOK, event)
 }
 
 // @Summary Create a new event
-// @Description Creates a new event in the database
+// @Description Creates a new event in the database. sport and level have to be values from the references, startsAt has to be in the future. startsAt and endsAt are RFC 3339 times, timezone is an IANA name defaulting to Europe/Prague and recurring occurrences keep the local time of day in it. With venueId the location and coordinates come from the venue.
 // @Tags events
 // @Accept json
 // @Produce json
 // @Param newEvent body models.EventInput true "Event object"
 // @Success 200 {object} models.Event
-// @Failure 400 {object} models.ErrorResponse
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid event, fields lists the problems"
 // @Failure 401 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Security BearerAuth
 // @Router /events [post]
 func (s *EventsService) CreateEvent(c *gin.Context) {
 	var inputEvent models.EventInput
-	err := c.BindJSON(&inputEvent)
+	if err := c.ShouldBindJSON(&inputEvent); err != nil {
+		log.Println("(CreateEvent) c.ShouldBindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Error while parsing request body"))
+
+		return
+	}
+
+	s.createEvent(c, inputEvent)
+}
+
+// createEvent validates the input and inserts the event owned by the current
+// user, writing the response. It backs creating, cloning and templates.
+func (s *EventsService) createEvent(c *gin.Context, inputEvent models.EventInput) {
+	if !s.validateEvent(c, &inputEvent, nil) {
+		return
+	}
+
+	series, err := parseRecurrenceInput(localDate(inputEvent.StartsAt, location(inputEvent.Timezone)), inputEvent.RecurrenceRule, inputEvent.ExceptionDates)
 	if err != nil {
-		log.Println("(CreateEvent) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
 	}
 
-	userID := c.GetString(constants.UserID_key)
+	status, ok := validateInitialStatus(inputEvent.Status)
+	if !ok {
+		c.JSON(http.StatusBadRequest, utils.GetError("status must be draft or published"))
 
-	newEvent := models.Event{}
+		return
+	}
 
-	if err := utils.CopyFields(&inputEvent, &newEvent); err != nil {
-		log.Println("(CopyFields):", err)
+	newEvent, err := buildEvent(inputEvent, c.GetString(constants.UserID_key), series, status)
+	if err != nil {
+		log.Println("(createEvent) buildEvent", err)
 		c.JSON(http.StatusInternalServerError, utils.GetError("Error creating event"))
 
 		return
 	}
 
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Println("(createEvent) db.Begin", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error creating event"))
+
+		return
+	}
+	defer tx.Rollback()
+
+	if err := insertEvent(tx, newEvent, series); err != nil {
+		log.Println("(createEvent) insertEvent", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Error creating event"))
+
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("(createEvent) tx.Commit", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error creating event"))
+
+		return
+	}
+
+	presentEvent(&newEvent)
+	c.JSON(http.StatusOK, newEvent)
+}
+
+// buildEvent turns a validated input into a new event owned by the user.
+func buildEvent(inputEvent models.EventInput, userID string, series *recurrence, status string) (models.Event, error) {
+	newEvent := models.Event{}
+
+	if err := utils.CopyFields(&inputEvent, &newEvent); err != nil {
+		return newEvent, err
+	}
+
 	newEvent.Owner_ID = userID
 	newEvent.Public_ID = utils.GenerateUUID()
 	newEvent.Created_At = time.Now()
+	newEvent.RecurrenceRule = series.Rule
+	newEvent.ExceptionDates = series.Exdates
+	newEvent.Status = status
+
+	return newEvent, nil
+}
 
-	query := "INSERT INTO events (name, sport, date, location, description, level, public_id, created_at, owner_id"
+// insertEvent stores the event together with the owner role and its tags.
+func insertEvent(tx *sql.Tx, newEvent models.Event, series *recurrence) error {
+	query := "INSERT INTO events (name, sport, starts_at, ends_at, timezone, location, venue_id, latitude, longitude, description, level, public_id, created_at, owner_id, capacity, recurrence_rule, recurrence_exdates, recurrence_end, status"
 
-	values := []interface{}{newEvent.Name, newEvent.Sport, newEvent.Date, newEvent.Location, newEvent.Description, newEvent.Level, newEvent.Public_ID, newEvent.Created_At, newEvent.Owner_ID}
+	values := []interface{}{newEvent.Name, newEvent.Sport, newEvent.StartsAt, newEvent.EndsAt, newEvent.Timezone, newEvent.Location, newEvent.VenueID, newEvent.Latitude, newEvent.Longitude, newEvent.Description, newEvent.Level, newEvent.Public_ID, newEvent.Created_At, newEvent.Owner_ID, newEvent.Capacity, series.Rule, pq.Array(series.Exdates), series.End, newEvent.Status}
 
 	if newEvent.Price != 0 {
 		query += ", price"
 		values = append(values, newEvent.Price)
 	}
 
-	query += ") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9"
-	if newEvent.Price != 0 {
-		query += ",$10"
+	placeholders := make([]string, len(values))
+	for i := range values {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
 	}
-	query += ")"
+	query += ") VALUES (" + strings.Join(placeholders, ",") + ")"
 
-	_, err = s.db.Exec(query, values...)
-	if err != nil {
-		log.Println("(CreateEvent) db.Exec", err)
-		c.JSON(http.StatusBadRequest, utils.GetError("Error creating event"))
+	if _, err := tx.Exec(query, values...); err != nil {
+		return err
+	}
 
-		return
+	_, err := tx.Exec("INSERT INTO event_roles (event_id, user_id, role) VALUES ($1, $2, $3)", newEvent.Public_ID, newEvent.Owner_ID, models.EventRoleOwner)
+	if err != nil {
+		return err
 	}
 
-	c.JSON(http.StatusOK, newEvent)
+	return saveTags(tx, newEvent.Public_ID, newEvent.Tags)
 }
 
-func (s *EventsService) validateUserIsOwnerOfEvent(c *gin.Context, eventId string) bool {
+// validateUserRole checks the role of the current user in the event and
+// writes the error response when it is not allowed.
+func (s *EventsService) validateUserRole(c *gin.Context, eventId string, allowed func(role string) bool, message string) bool {
 	userID := c.GetString(constants.UserID_key)
 
-	var ownerID string
-	err := s.db.QueryRow("SELECT owner_id FROM events WHERE public_id = $1", eventId).Scan(&ownerID)
+	role, err := roles.GetUserRole(s.db, eventId, userID)
 	if err != nil {
-		log.Println("(UpdateEvent) db.QueryRow", err)
-		c.JSON(http.StatusBadRequest, utils.GetError("Error updating event"))
+		log.Println("(validateUserRole) roles.GetUserRole", err)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error updating event"))
+		}
 
 		return false
 	}
 
-	if ownerID != userID {
-		c.JSON(http.StatusForbidden, utils.GetError("You are not the owner of this event"))
+	if !allowed(role) {
+		c.JSON(http.StatusForbidden, utils.GetError(message))
 
 		return false
 	}
@@ -217,15 +529,69 @@ func (s *EventsService) validateUserIsOwnerOfEvent(c *gin.Context, eventId strin
 	return true
 }
 
+func (s *EventsService) validateUserIsOwnerOfEvent(c *gin.Context, eventId string) bool {
+	return s.validateUserRole(c, eventId, func(role string) bool { return role == models.EventRoleOwner }, "You are not the owner of this event")
+}
+
+// validateUserCanManageEvent lets through the owner and co-organizers.
+func (s *EventsService) validateUserCanManageEvent(c *gin.Context, eventId string) bool {
+	return s.validateUserRole(c, eventId, roles.CanManage, "You are not an organizer of this event")
+}
+
+// errCapacityBelowParticipants rejects lowering the capacity under the
+// number of participants who already joined.
+var errCapacityBelowParticipants = errors.New("capacity cannot be lower than the number of participants")
+
+func (s *EventsService) updateEventRow(eventId string, updates models.EventInput, series *recurrence) error {
+	query := "UPDATE events SET name = $1, sport = $2, starts_at = $3, ends_at = $4, timezone = $5, location = $6, latitude = $7, longitude = $8, price = $9, description = $10, level = $11, capacity = $12, recurrence_rule = $13, recurrence_exdates = $14, recurrence_end = $15, venue_id = $16"
+	values := []interface{}{updates.Name, updates.Sport, updates.StartsAt, updates.EndsAt, updates.Timezone, updates.Location, updates.Latitude, updates.Longitude, updates.Price, updates.Description, updates.Level, updates.Capacity, series.Rule, pq.Array(series.Exdates), series.End, updates.VenueID}
+
+	query += " WHERE public_id = $17 AND deleted_at IS NULL"
+	values = append(values, eventId)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// JoinEvent locks the same row, so no participant can join between the
+	// count and the update
+	if updates.Capacity != nil {
+		if _, err := tx.Exec("SELECT 1 FROM events WHERE public_id = $1 FOR UPDATE", eventId); err != nil {
+			return err
+		}
+
+		var participantsCount int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM event_participants WHERE event_id = $1", eventId).Scan(&participantsCount); err != nil {
+			return err
+		}
+
+		if int(*updates.Capacity) < participantsCount {
+			return errCapacityBelowParticipants
+		}
+	}
+
+	if _, err := tx.Exec(query, values...); err != nil {
+		return err
+	}
+
+	if err := saveTags(tx, eventId, updates.Tags); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 // @Summary Update an event
-// @Description Update an existing event with the given event ID
+// @Description Update an existing event with the given event ID. For recurring events this updates the whole series. The status is changed only through the publish, cancel and complete endpoints. Available to the owner and co-organizers.
 // @Tags events
 // @Accept json
 // @Produce json
 // @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
 // @Param event body models.EventInput true "Event object that needs to be updated"
 // @Success 200 {object} models.Event
-// @Failure 400 {object} models.ErrorResponse
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid event, fields lists the problems"
 // @Failure 401 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Security BearerAuth
@@ -242,19 +608,37 @@ func (s *EventsService) UpdateEvent(c *gin.Context) {
 
 	eventId := c.Param("eventId")
 
-	if !s.validateUserIsOwnerOfEvent(c, eventId) {
+	if !s.validateUserCanManageEvent(c, eventId) {
 		return
 	}
 
-	query := "UPDATE events SET name = $1, sport = $2, date = $3, location = $4, price = $5, description = $6, level = $7"
-	values := []interface{}{updates.Name, updates.Sport, updates.Date, updates.Location, updates.Price, updates.Description, updates.Level}
+	var startsAt time.Time
+	if err := s.db.QueryRow("SELECT starts_at FROM events WHERE public_id = $1 AND deleted_at IS NULL", eventId).Scan(&startsAt); err != nil {
+		log.Println("(UpdateEvent) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error updating event"))
 
-	query += " WHERE public_id = $8"
-	values = append(values, eventId)
+		return
+	}
 
-	_, err = s.db.Exec(query, values...)
+	if !s.validateEvent(c, &updates, &startsAt) {
+		return
+	}
+
+	series, err := parseRecurrenceInput(localDate(updates.StartsAt, location(updates.Timezone)), updates.RecurrenceRule, updates.ExceptionDates)
 	if err != nil {
-		log.Println("(UpdateEvent) db.Exec", err)
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
+	}
+
+	err = s.updateEventRow(eventId, updates, series)
+	if err == errCapacityBelowParticipants {
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
+	}
+	if err != nil {
+		log.Println("(UpdateEvent) updateEventRow", err)
 		c.JSON(http.StatusBadRequest, utils.GetError("Error updating event"))
 
 		return
@@ -264,24 +648,41 @@ func (s *EventsService) UpdateEvent(c *gin.Context) {
 }
 
 // @Summary Delete an event
-// @Description Delete an existing event with the given event ID
+// @Description Delete an existing event with the given event ID. For recurring events this deletes the whole series. Events that already received requests have to be cancelled instead. Available to the event owner and co-organizers. The event can be restored for 14 days, after 30 days it is purged for good.
 // @Tags events
 // @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
 // @Success 200 {object} models.Event
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Security BearerAuth
 // @Router /events/{eventId} [delete]
 func (s *EventsService) DeleteEvent(c *gin.Context) {
 	eventId := c.Param("eventId")
 
-	if !s.validateUserIsOwnerOfEvent(c, eventId) {
+	if !s.validateUserCanManageEvent(c, eventId) {
+		return
+	}
+
+	var hasRequests bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM email_requests WHERE event_id = $1)", eventId).Scan(&hasRequests)
+	if err != nil {
+		log.Println("(DeleteEvent) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error deleting event"))
+
+		return
+	}
+
+	if hasRequests {
+		c.JSON(http.StatusConflict, utils.GetError("Event already has requests, cancel it instead"))
+
 		return
 	}
 
-	query := `DELETE FROM events WHERE public_id = $1`
-	_, err := s.db.Exec(query, eventId)
+	// images stay in the storage until the event is purged
+	query := `UPDATE events SET deleted_at = $1 WHERE public_id = $2 AND deleted_at IS NULL`
+	_, err = s.db.Exec(query, time.Now(), eventId)
 	if err != nil {
 		log.Println("(DeleteEvent) db.Exec", err)
 		c.JSON(http.StatusBadRequest, utils.GetError("Error deleting event"))
@@ -291,3 +692,51 @@ func (s *EventsService) DeleteEvent(c *gin.Context) {
 
 	c.Status(http.StatusOK)
 }
+
+// @Summary Restore a deleted event
+// @Description Restores an event deleted in the last 14 days. Available to the event owner and co-organizers.
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Success 200 {object} models.Event
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/restore [post]
+func (s *EventsService) RestoreEvent(c *gin.Context) {
+	eventId := c.Param("eventId")
+	userID := c.GetString(constants.UserID_key)
+
+	query := `
+		UPDATE events SET deleted_at = NULL
+		WHERE public_id = $1 AND deleted_at > $3 AND public_id IN (
+			SELECT event_roles.event_id FROM event_roles WHERE event_roles.user_id = $2 AND event_roles.role IN ` + roles.OrganizerRoles + `
+		)
+	`
+	res, err := s.db.Exec(query, eventId, userID, time.Now().Add(-constants.RestoreWindow))
+	if err != nil {
+		log.Println("(RestoreEvent) db.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error restoring event"))
+
+		return
+	}
+
+	if restored, err := res.RowsAffected(); err != nil || restored == 0 {
+		c.JSON(http.StatusNotFound, utils.GetError("No restorable event found"))
+
+		return
+	}
+
+	var event models.EventWithOwner
+	err = s.db.QueryRow("SELECT "+columns+" FROM events WHERE public_id = $1", eventId).Scan(getColumnForEvent(&event)...)
+	if err != nil {
+		log.Println("(RestoreEvent) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error reading restored event"))
+
+		return
+	}
+	presentEvent(&event.Event)
+
+	c.JSON(http.StatusOK, event.Event)
+}
@@ -0,0 +1,240 @@
+package events
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/pkg/export"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+var eventExportHeader = []string{"id", "name", "sport", "level", "status", "startsAt", "endsAt", "timezone", "location", "venueId", "latitude", "longitude",
+	"price", "capacity", "spotsLeft", "recurrenceRule", "tags", "description"}
+
+var attendeeExportHeader = []string{"name", "email", "approvedAt"}
+
+// startExport sets the download headers and starts the table in the
+// requested format, writing the error response when it fails.
+func startExport(c *gin.Context, filename string, header []string) (export.Writer, bool) {
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if format != export.FormatCSV && format != export.FormatXLSX {
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid format parameter, expected csv or xlsx"))
+
+		return nil, false
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+"."+format+`"`)
+	c.Status(http.StatusOK)
+
+	writer, err := export.NewWriter(format, c.Writer, header)
+	if err != nil {
+		log.Println("(startExport) export.NewWriter", err)
+
+		return nil, false
+	}
+
+	return writer, true
+}
+
+func optionalFloat(value *float64) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	return *value
+}
+
+func optionalString(value *string) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	return *value
+}
+
+func optionalInt(value *int) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	return *value
+}
+
+func optionalCapacity(value *uint16) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	return int(*value)
+}
+
+func optionalTime(value *time.Time) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	return *value
+}
+
+// @Summary Export my events
+// @Description Streams the events owned by the current user as CSV or XLSX. Accepts the filters and sorting of GET /user/me/events, pagination does not apply.
+// @Tags events
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "File format" Enums(csv, xlsx) default(csv)
+// @Param period query string false "Upcoming events, past events or both" Enums(all, upcoming, past) default(all)
+// @Param status query string false "Event status" Enums(draft, published, cancelled, completed)
+// @Param sort query string false "Sort order, a leading minus means descending" Enums(date, -date, price, -price, createdAt, -createdAt, distance)
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /user/me/events/export [get]
+func (s *EventsService) ExportMyEvents(c *gin.Context) {
+	scope := ownedScope(c.GetString(constants.UserID_key))
+
+	filters, err := parseEventFilters(c, scope)
+	if err != nil {
+		log.Println("(ExportMyEvents) parseEventFilters", err)
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
+	}
+
+	if filters.Expand {
+		c.JSON(http.StatusBadRequest, utils.GetError("expand is not supported by the export"))
+
+		return
+	}
+
+	q, ok := s.listQuery(c, scope, filters)
+	if !ok {
+		return
+	}
+
+	query, args := q.build()
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		log.Println("(ExportMyEvents) db.Query", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error exporting events"))
+
+		return
+	}
+	defer rows.Close()
+
+	writer, ok := startExport(c, "events", eventExportHeader)
+	if !ok {
+		return
+	}
+
+	// the response has already started, errors can only end it early
+	for rows.Next() {
+		var event models.EventWithOwner
+		if err := rows.Scan(append(getColumnForEvent(&event), &event.DistanceKm)...); err != nil {
+			log.Println("(ExportMyEvents) rows.Scan", err)
+
+			return
+		}
+		presentEvent(&event.Event)
+
+		var rule interface{}
+		if event.RecurrenceRule != nil {
+			rule = *event.RecurrenceRule
+		}
+
+		err := writer.Write([]interface{}{event.Public_ID, event.Name, event.Sport, event.Level, event.Status, event.StartsAt, optionalTime(event.EndsAt),
+			event.Timezone, event.Location, optionalString(event.VenueID), optionalFloat(event.Latitude), optionalFloat(event.Longitude), int(event.Price), optionalCapacity(event.Capacity),
+			optionalInt(event.SpotsLeft), rule, event.Tags, event.DescriptionText})
+		if err != nil {
+			log.Println("(ExportMyEvents) writer.Write", err)
+
+			return
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("(ExportMyEvents) rows.Err", err)
+
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Println("(ExportMyEvents) writer.Close", err)
+	}
+}
+
+// @Summary Export event attendees
+// @Description Streams the requesters approved for the event as CSV or XLSX. Available only to the event owner.
+// @Tags events
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param format query string false "File format" Enums(csv, xlsx) default(csv)
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/attendees/export [get]
+func (s *EventsService) ExportAttendees(c *gin.Context) {
+	eventId := c.Param("eventId")
+
+	if !s.validateUserIsOwnerOfEvent(c, eventId) {
+		return
+	}
+
+	query := `
+		SELECT users.name, users.email, email_requests.approved_at
+		FROM email_requests
+		JOIN users ON users.id = email_requests.requester_id AND users.deleted_at IS NULL
+		WHERE email_requests.event_id = $1 AND email_requests.approved = true
+		ORDER BY email_requests.approved_at, users.name
+	`
+	rows, err := s.db.Query(query, eventId)
+	if err != nil {
+		log.Println("(ExportAttendees) db.Query", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error exporting attendees"))
+
+		return
+	}
+	defer rows.Close()
+
+	writer, ok := startExport(c, "attendees-"+eventId, attendeeExportHeader)
+	if !ok {
+		return
+	}
+
+	for rows.Next() {
+		var name, email string
+		var approvedAt *time.Time
+		if err := rows.Scan(&name, &email, &approvedAt); err != nil {
+			log.Println("(ExportAttendees) rows.Scan", err)
+
+			return
+		}
+
+		if err := writer.Write([]interface{}{name, email, optionalTime(approvedAt)}); err != nil {
+			log.Println("(ExportAttendees) writer.Write", err)
+
+			return
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("(ExportAttendees) rows.Err", err)
+
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Println("(ExportAttendees) writer.Close", err)
+	}
+}
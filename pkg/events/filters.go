@@ -0,0 +1,368 @@
+package events
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/utils"
+	"github.com/lib/pq"
+)
+
+const (
+	dateLayout     = "2006-01-02"
+	maxSearchTerms = 10
+
+	periodAll      = "all"
+	periodUpcoming = "upcoming"
+	periodPast     = "past"
+)
+
+// eventScope narrows a listing down to the events of the current user.
+// Personal listings include drafts and past events unless filtered out.
+type eventScope struct {
+	condition func(q *eventQuery) string
+	personal  bool
+}
+
+type eventFilters struct {
+	Search   string
+	Sport    string
+	Level    string
+	Tags     []string
+	Location string
+	Venue    string
+	DateFrom *time.Time
+	DateTo   *time.Time
+	PriceMin *uint16
+	PriceMax *uint16
+	Free     bool
+	Point    *geoPoint
+	RadiusKm *float64
+	Expand   bool
+	Status   string
+	// IncludeDrafts lists drafts when no status is requested
+	IncludeDrafts bool
+	Sort          string
+	Period        string
+}
+
+func parseDateParam(c *gin.Context, name string) (*time.Time, error) {
+	value := strings.TrimSpace(c.Query(name))
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, errors.New("Invalid " + name + " parameter, expected YYYY-MM-DD")
+	}
+
+	return &date, nil
+}
+
+func parsePriceParam(c *gin.Context, name string) (*uint16, error) {
+	value := strings.TrimSpace(c.Query(name))
+	if value == "" {
+		return nil, nil
+	}
+
+	price, err := strconv.ParseUint(value, 10, 16)
+	if err != nil {
+		return nil, errors.New("Invalid " + name + " parameter, expected a non-negative integer")
+	}
+
+	result := uint16(price)
+
+	return &result, nil
+}
+
+// buildSearchQuery turns free text into a prefix-matching tsquery, so "beh"
+// finds "Běh" once both sides went through unaccent. Only letters and digits
+// are kept, which means no tsquery operator can be smuggled in.
+func buildSearchQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
+	}
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, strings.ToLower(word)+":*")
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+func parseFloatParam(c *gin.Context, name string) (*float64, error) {
+	value := strings.TrimSpace(c.Query(name))
+	if value == "" {
+		return nil, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return nil, errors.New("Invalid " + name + " parameter, expected a number")
+	}
+
+	return &number, nil
+}
+
+func parsePeriod(c *gin.Context, scope eventScope) (string, error) {
+	if !scope.personal {
+		includePast, err := parseBoolParam(c, "includePast")
+		if err != nil {
+			return "", err
+		}
+
+		if includePast {
+			return periodAll, nil
+		}
+
+		return periodUpcoming, nil
+	}
+
+	switch period := strings.TrimSpace(c.DefaultQuery("period", periodAll)); period {
+	case periodAll, periodUpcoming, periodPast:
+		return period, nil
+	default:
+		return "", errors.New("Invalid period parameter, expected all, upcoming or past")
+	}
+}
+
+func parseEventFilters(c *gin.Context, scope eventScope) (*eventFilters, error) {
+	var err error
+	filters := &eventFilters{
+		Sport:    strings.ToLower(strings.TrimSpace(c.Query("sport"))),
+		Level:    strings.ToLower(strings.TrimSpace(c.Query("level"))),
+		Location: strings.TrimSpace(c.Query("location")),
+		Venue:    strings.TrimSpace(c.Query("venue")),
+		Status:   strings.TrimSpace(c.Query("status")),
+	}
+
+	for _, value := range c.QueryArray("tags") {
+		filters.Tags = append(filters.Tags, strings.Split(value, ",")...)
+	}
+	filters.Tags = normalizeTags(filters.Tags)
+
+	switch {
+	case filters.Status == models.EventStatusDraft && scope.personal:
+	case filters.Status == "", filters.Status == models.EventStatusPublished, filters.Status == models.EventStatusCancelled, filters.Status == models.EventStatusCompleted:
+	default:
+		return nil, errors.New("Invalid status parameter, expected published, cancelled or completed")
+	}
+	filters.IncludeDrafts = scope.personal
+
+	if text := strings.TrimSpace(c.Query("q")); text != "" {
+		filters.Search = buildSearchQuery(text)
+		if filters.Search == "" {
+			return nil, errors.New("Invalid q parameter, expected at least one word")
+		}
+	}
+
+	if filters.DateFrom, err = parseDateParam(c, "dateFrom"); err != nil {
+		return nil, err
+	}
+
+	if filters.DateTo, err = parseDateParam(c, "dateTo"); err != nil {
+		return nil, err
+	}
+
+	if filters.DateFrom != nil && filters.DateTo != nil && filters.DateFrom.After(*filters.DateTo) {
+		return nil, errors.New("dateFrom must not be after dateTo")
+	}
+
+	if filters.Expand, err = parseBoolParam(c, "expand"); err != nil {
+		return nil, err
+	}
+
+	if filters.Expand {
+		if filters.DateFrom == nil || filters.DateTo == nil {
+			return nil, errors.New("expand requires dateFrom and dateTo")
+		}
+
+		if filters.DateTo.Sub(*filters.DateFrom) > maxExpandWindowDays*24*time.Hour {
+			return nil, errors.New("expand window must not be longer than 366 days")
+		}
+	}
+
+	if filters.PriceMin, err = parsePriceParam(c, "priceMin"); err != nil {
+		return nil, err
+	}
+
+	if filters.PriceMax, err = parsePriceParam(c, "priceMax"); err != nil {
+		return nil, err
+	}
+
+	if filters.PriceMin != nil && filters.PriceMax != nil && *filters.PriceMin > *filters.PriceMax {
+		return nil, errors.New("priceMin must not be greater than priceMax")
+	}
+
+	if filters.Free, err = parseBoolParam(c, "free"); err != nil {
+		return nil, err
+	}
+
+	if filters.Free && filters.PriceMin != nil && *filters.PriceMin > 0 {
+		return nil, errors.New("free cannot be combined with a positive priceMin")
+	}
+
+	lat, err := parseFloatParam(c, "lat")
+	if err != nil {
+		return nil, err
+	}
+
+	lng, err := parseFloatParam(c, "lng")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utils.ValidateCoordinates(lat, lng); err != nil {
+		return nil, errors.New("Invalid lat/lng parameters, " + err.Error())
+	}
+
+	if lat != nil {
+		filters.Point = &geoPoint{Latitude: *lat, Longitude: *lng}
+	}
+
+	if filters.RadiusKm, err = parseFloatParam(c, "radiusKm"); err != nil {
+		return nil, err
+	}
+
+	if filters.RadiusKm != nil {
+		if filters.Point == nil {
+			return nil, errors.New("radiusKm requires lat and lng")
+		}
+
+		if *filters.RadiusKm <= 0 || *filters.RadiusKm > maxRadiusKm {
+			return nil, errors.New("Invalid radiusKm parameter, expected a number between 0 and 20000")
+		}
+	}
+
+	if filters.Period, err = parsePeriod(c, scope); err != nil {
+		return nil, err
+	}
+
+	today := utils.TruncateToDate(time.Now())
+	if filters.Expand && filters.Period == periodUpcoming && filters.DateFrom.Before(today) {
+		filters.DateFrom = &today
+	}
+
+	if filters.Sort, err = parseSort(c, filters); err != nil {
+		return nil, err
+	}
+
+	return filters, nil
+}
+
+func parseSort(c *gin.Context, filters *eventFilters) (string, error) {
+	sort := strings.TrimSpace(c.Query("sort"))
+
+	switch {
+	case sort == "" && filters.Expand:
+		return "date", nil
+	case sort == "" && filters.Search != "":
+		return sortRelevance, nil
+	case sort == "":
+		return sortNewest, nil
+	}
+
+	if _, ok := sortOptions[sort]; !ok {
+		return "", errors.New("Invalid sort parameter, expected date, -date, price, -price, createdAt, -createdAt or distance")
+	}
+
+	if sort == sortDistance && filters.Point == nil {
+		return "", errors.New("sort=distance requires lat and lng")
+	}
+
+	if filters.Expand && sort != "date" {
+		return "", errors.New("expanded occurrences are always sorted by date")
+	}
+
+	return sort, nil
+}
+
+func (f *eventFilters) apply(q *eventQuery) {
+	if f.Status != "" {
+		q.where("events.status = " + q.arg(f.Status))
+	} else if !f.IncludeDrafts {
+		q.where("events.status != " + q.arg(models.EventStatusDraft))
+	}
+
+	if f.Search != "" {
+		tsQuery := "to_tsquery('simple', immutable_unaccent(" + q.arg(f.Search) + "))"
+		q.where("events.search_vector @@ " + tsQuery)
+		q.rank = "ts_rank(events.search_vector, " + tsQuery + ")"
+	}
+
+	if f.Sport != "" {
+		q.where("LOWER(events.sport) = LOWER(" + q.arg(f.Sport) + ")")
+	}
+
+	if f.Level != "" {
+		q.where("LOWER(events.level) = " + q.arg(f.Level))
+	}
+
+	// every requested tag has to be present
+	if len(f.Tags) > 0 {
+		q.where("(SELECT COUNT(*) FROM event_tags JOIN tags ON tags.id = event_tags.tag_id WHERE event_tags.event_id = events.public_id AND tags.value = ANY(" +
+			q.arg(pq.Array(f.Tags)) + ")) = " + q.arg(len(f.Tags)))
+	}
+
+	if f.Location != "" {
+		q.where("events.location ILIKE " + q.arg("%"+escapeLike(f.Location)+"%"))
+	}
+
+	if f.Venue != "" {
+		q.where("events.venue_id = " + q.arg(f.Venue))
+	}
+
+	// a recurring series that started earlier can still have occurrences later
+	if f.DateFrom != nil {
+		from := q.arg(*f.DateFrom)
+		q.where("(events.date >= " + from + " OR (events.recurrence_rule IS NOT NULL AND (events.recurrence_end IS NULL OR events.recurrence_end >= " + from + ")))")
+	}
+
+	if f.DateTo != nil {
+		q.where("events.date <= " + q.arg(*f.DateTo))
+	}
+
+	if f.PriceMin != nil {
+		q.where("events.price >= " + q.arg(*f.PriceMin))
+	}
+
+	if f.PriceMax != nil {
+		q.where("events.price <= " + q.arg(*f.PriceMax))
+	}
+
+	if f.Free {
+		q.where("events.price = 0")
+	}
+
+	if f.Point != nil {
+		q.distance = distanceExpression(q, *f.Point)
+
+		if f.RadiusKm != nil {
+			applyRadius(q, *f.Point, *f.RadiusKm, q.distance)
+		}
+	}
+
+	// today is the local day of the event, so an evening event stays listed
+	// until midnight where it takes place. A series with occurrences left is
+	// upcoming even when it started in the past.
+	today := "(now() AT TIME ZONE events.timezone)::date"
+	upcoming := "(events.date IS NULL OR events.date >= " + today + " OR (events.recurrence_rule IS NOT NULL AND (events.recurrence_end IS NULL OR events.recurrence_end >= " + today + ")))"
+	switch f.Period {
+	case periodUpcoming:
+		q.where(upcoming)
+	case periodPast:
+		q.where("NOT " + upcoming)
+	}
+}
@@ -0,0 +1,47 @@
+package events
+
+import (
+	"math"
+	"strconv"
+)
+
+const (
+	earthRadiusKm = 6371.0
+	kmPerDegree   = 111.045
+	maxRadiusKm   = 20000.0
+)
+
+type geoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// distanceExpression returns the haversine great-circle distance in kilometres
+// between the event and the given point, using plain Postgres math only.
+func distanceExpression(q *eventQuery, point geoPoint) string {
+	lat := q.arg(point.Latitude)
+	lng := q.arg(point.Longitude)
+
+	return "(" + strconv.FormatFloat(2*earthRadiusKm, 'f', -1, 64) + " * ASIN(LEAST(1, SQRT(" +
+		"POWER(SIN(RADIANS(events.latitude - " + lat + ") / 2), 2) + " +
+		"COS(RADIANS(" + lat + ")) * COS(RADIANS(events.latitude)) * " +
+		"POWER(SIN(RADIANS(events.longitude - " + lng + ") / 2), 2)))))"
+}
+
+// applyRadius narrows the search to a bounding box first, which can use the
+// coordinates index, and then to the exact radius.
+func applyRadius(q *eventQuery, point geoPoint, radiusKm float64, distance string) {
+	latDelta := radiusKm / kmPerDegree
+	q.where("events.latitude BETWEEN " + q.arg(point.Latitude-latDelta) + " AND " + q.arg(point.Latitude+latDelta))
+
+	// near the poles or across the antimeridian the longitude box would wrap
+	cosLat := math.Cos(point.Latitude * math.Pi / 180)
+	if cosLat > 0.01 {
+		lngDelta := radiusKm / (kmPerDegree * cosLat)
+		if point.Longitude-lngDelta >= -180 && point.Longitude+lngDelta <= 180 {
+			q.where("events.longitude BETWEEN " + q.arg(point.Longitude-lngDelta) + " AND " + q.arg(point.Longitude+lngDelta))
+		}
+	}
+
+	q.where(distance + " <= " + q.arg(radiusKm))
+}
@@ -0,0 +1,491 @@
+package events
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+const (
+	imageKindCover      = "cover"
+	imageKindGallery    = "gallery"
+	maxImageBytes       = 5 << 20
+	maxImagePixels      = 40_000_000
+	maxImageDimension   = 1920
+	thumbnailDimension  = 400
+	maxGalleryImages    = 10
+	imageJPEGQuality    = 85
+	thumbnailSuffix     = "_thumb"
+	imageColumns        = "public_id, image_url, thumbnail_url, width, height, created_at"
+	imageStorageColumns = "image_key, thumbnail_key"
+)
+
+type processedImage struct {
+	data      []byte
+	extension string
+	thumbnail []byte
+	width     int
+	height    int
+}
+
+// processImage validates the upload and re-encodes it, which also strips
+// metadata such as GPS coordinates, together with a JPEG thumbnail.
+func processImage(header *multipart.FileHeader) (*processedImage, error) {
+	if header.Size > maxImageBytes {
+		return nil, errors.New("image must be at most 5 MB")
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxImageBytes {
+		return nil, errors.New("image must be at most 5 MB")
+	}
+
+	contentType := http.DetectContentType(data)
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		return nil, errors.New("image must be a JPEG or PNG")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("image could not be read")
+	}
+
+	if config.Width*config.Height > maxImagePixels {
+		return nil, errors.New("image resolution is too large")
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("image could not be read")
+	}
+
+	resized := utils.ResizeToFit(decoded, maxImageDimension, maxImageDimension)
+	result := &processedImage{width: resized.Bounds().Dx(), height: resized.Bounds().Dy()}
+
+	var buffer bytes.Buffer
+	if contentType == "image/png" {
+		result.extension = ".png"
+		err = png.Encode(&buffer, resized)
+	} else {
+		result.extension = ".jpg"
+		err = jpeg.Encode(&buffer, resized, &jpeg.Options{Quality: imageJPEGQuality})
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.data = buffer.Bytes()
+
+	var thumbnail bytes.Buffer
+	thumbnailImage := utils.FlattenOnWhite(utils.ResizeToFit(resized, thumbnailDimension, thumbnailDimension))
+	if err := jpeg.Encode(&thumbnail, thumbnailImage, &jpeg.Options{Quality: imageJPEGQuality}); err != nil {
+		return nil, err
+	}
+	result.thumbnail = thumbnail.Bytes()
+
+	return result, nil
+}
+
+// storeImage saves the files and inserts the image row in the transaction.
+// It returns the storage keys, which the caller deletes when the transaction
+// does not commit.
+func (s *EventsService) storeImage(tx *sql.Tx, eventId string, kind string, processed *processedImage) (*models.EventImage, []string, error) {
+	stored := models.EventImage{
+		ID:        utils.GenerateUUID(),
+		Width:     processed.width,
+		Height:    processed.height,
+		CreatedAt: time.Now(),
+	}
+
+	imageKey := "events/" + eventId + "/" + stored.ID + processed.extension
+	thumbnailKey := "events/" + eventId + "/" + stored.ID + thumbnailSuffix + ".jpg"
+
+	var err error
+	if stored.URL, err = s.storage.Save(imageKey, processed.data); err != nil {
+		return nil, nil, err
+	}
+
+	if stored.ThumbnailURL, err = s.storage.Save(thumbnailKey, processed.thumbnail); err != nil {
+		s.deleteStoredFiles(imageKey)
+
+		return nil, nil, err
+	}
+
+	query := `
+		INSERT INTO event_images (public_id, event_id, kind, image_key, image_url, thumbnail_key, thumbnail_url, width, height, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err = tx.Exec(query, stored.ID, eventId, kind, imageKey, stored.URL, thumbnailKey, stored.ThumbnailURL, stored.Width, stored.Height, stored.CreatedAt)
+	if err != nil {
+		s.deleteStoredFiles(imageKey, thumbnailKey)
+
+		return nil, nil, err
+	}
+
+	return &stored, []string{imageKey, thumbnailKey}, nil
+}
+
+func (s *EventsService) deleteStoredFiles(keys ...string) {
+	for _, key := range keys {
+		if err := s.storage.Delete(key); err != nil {
+			log.Println("(deleteStoredFiles) storage.Delete", key, err)
+		}
+	}
+}
+
+// deleteImageRows removes the matching image rows and returns the storage
+// keys of their files, which are deleted once the removal commits.
+func deleteImageRows(tx *sql.Tx, where string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query("DELETE FROM event_images WHERE "+where+" RETURNING "+imageStorageColumns, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var imageKey, thumbnailKey string
+		if err := rows.Scan(&imageKey, &thumbnailKey); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, imageKey, thumbnailKey)
+	}
+
+	return keys, rows.Err()
+}
+
+// deleteImages removes the matching image rows and their stored files.
+func (s *EventsService) deleteImages(where string, args ...interface{}) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	keys, err := deleteImageRows(tx, where, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	s.deleteStoredFiles(keys...)
+
+	return len(keys) / 2, nil
+}
+
+// lockEvent locks the event row for the rest of the transaction, the same
+// lock JoinEvent takes.
+func lockEvent(tx *sql.Tx, eventId string) error {
+	_, err := tx.Exec("SELECT 1 FROM events WHERE public_id = $1 FOR UPDATE", eventId)
+
+	return err
+}
+
+func (s *EventsService) getGallery(eventId string) ([]models.EventImage, error) {
+	rows, err := s.db.Query("SELECT "+imageColumns+" FROM event_images WHERE event_id = $1 AND kind = $2 ORDER BY created_at, id", eventId, imageKindGallery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gallery := []models.EventImage{}
+	for rows.Next() {
+		var item models.EventImage
+		if err := rows.Scan(&item.ID, &item.URL, &item.ThumbnailURL, &item.Width, &item.Height, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		gallery = append(gallery, item)
+	}
+
+	return gallery, rows.Err()
+}
+
+// @Summary Upload event cover image
+// @Description Uploads a JPEG or PNG cover image of at most 5 MB, replacing the previous one. A thumbnail is generated automatically.
+// @Tags events
+// @Accept multipart/form-data
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param image formData file true "Cover image"
+// @Success 200 {object} models.EventImage
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/cover [put]
+func (s *EventsService) UploadCoverImage(c *gin.Context) {
+	eventId := c.Param("eventId")
+
+	if !s.validateUserCanManageEvent(c, eventId) {
+		return
+	}
+
+	header, err := c.FormFile("image")
+	if err != nil {
+		log.Println("(UploadCoverImage) c.FormFile", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("image file is required"))
+
+		return
+	}
+
+	processed, err := processImage(header)
+	if err != nil {
+		log.Println("(UploadCoverImage) processImage", err)
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Println("(UploadCoverImage) db.Begin", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error uploading image"))
+
+		return
+	}
+	defer tx.Rollback()
+
+	// the old cover stays in place until the new one is stored, its files are
+	// deleted only after the swap commits
+	if err := lockEvent(tx, eventId); err != nil {
+		log.Println("(UploadCoverImage) lockEvent", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error uploading image"))
+
+		return
+	}
+
+	oldKeys, err := deleteImageRows(tx, "event_id = $1 AND kind = $2", eventId, imageKindCover)
+	if err != nil {
+		log.Println("(UploadCoverImage) deleteImageRows", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error uploading image"))
+
+		return
+	}
+
+	cover, keys, err := s.storeImage(tx, eventId, imageKindCover, processed)
+	if err != nil {
+		log.Println("(UploadCoverImage) storeImage", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error uploading image"))
+
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("(UploadCoverImage) tx.Commit", err)
+		s.deleteStoredFiles(keys...)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error uploading image"))
+
+		return
+	}
+
+	s.deleteStoredFiles(oldKeys...)
+
+	c.JSON(http.StatusOK, cover)
+}
+
+// @Summary Delete event cover image
+// @Description Removes the cover image of the event
+// @Tags events
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Success 200
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/cover [delete]
+func (s *EventsService) DeleteCoverImage(c *gin.Context) {
+	eventId := c.Param("eventId")
+
+	if !s.validateUserCanManageEvent(c, eventId) {
+		return
+	}
+
+	deleted, err := s.deleteImages("event_id = $1 AND kind = $2", eventId, imageKindCover)
+	if err != nil {
+		log.Println("(DeleteCoverImage) deleteImages", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error deleting image"))
+
+		return
+	}
+
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, utils.GetError("Image not found"))
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Upload event gallery images
+// @Description Adds JPEG or PNG images of at most 5 MB each to the event gallery, up to 10 images per event
+// @Tags events
+// @Accept multipart/form-data
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param images formData file true "Gallery images, the field can be repeated"
+// @Success 200 {array} models.EventImage
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/gallery [post]
+func (s *EventsService) UploadGalleryImages(c *gin.Context) {
+	eventId := c.Param("eventId")
+
+	if !s.validateUserCanManageEvent(c, eventId) {
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["images"]) == 0 {
+		log.Println("(UploadGalleryImages) c.MultipartForm", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("at least one image file is required"))
+
+		return
+	}
+	headers := form.File["images"]
+
+	if len(headers) > maxGalleryImages {
+		c.JSON(http.StatusBadRequest, utils.GetError("gallery can contain at most 10 images"))
+
+		return
+	}
+
+	// validate everything first so a bad file does not leave a half upload
+	processed := make([]*processedImage, 0, len(headers))
+	for _, header := range headers {
+		result, err := processImage(header)
+		if err != nil {
+			log.Println("(UploadGalleryImages) processImage", err)
+			c.JSON(http.StatusBadRequest, utils.GetError(header.Filename+": "+err.Error()))
+
+			return
+		}
+
+		processed = append(processed, result)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Println("(UploadGalleryImages) db.Begin", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error uploading images"))
+
+		return
+	}
+	defer tx.Rollback()
+
+	// the lock keeps concurrent uploads from passing the count together
+	if err := lockEvent(tx, eventId); err != nil {
+		log.Println("(UploadGalleryImages) lockEvent", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error uploading images"))
+
+		return
+	}
+
+	var existing int
+	err = tx.QueryRow("SELECT COUNT(*) FROM event_images WHERE event_id = $1 AND kind = $2", eventId, imageKindGallery).Scan(&existing)
+	if err != nil {
+		log.Println("(UploadGalleryImages) tx.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error uploading images"))
+
+		return
+	}
+
+	if existing+len(headers) > maxGalleryImages {
+		c.JSON(http.StatusBadRequest, utils.GetError("gallery can contain at most 10 images"))
+
+		return
+	}
+
+	images := []models.EventImage{}
+	storedKeys := []string{}
+	for _, result := range processed {
+		stored, keys, err := s.storeImage(tx, eventId, imageKindGallery, result)
+		if err != nil {
+			log.Println("(UploadGalleryImages) storeImage", err)
+			s.deleteStoredFiles(storedKeys...)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error uploading images"))
+
+			return
+		}
+
+		images = append(images, *stored)
+		storedKeys = append(storedKeys, keys...)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("(UploadGalleryImages) tx.Commit", err)
+		s.deleteStoredFiles(storedKeys...)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error uploading images"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, images)
+}
+
+// @Summary Delete event gallery image
+// @Description Removes a single image from the event gallery
+// @Tags events
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param imageId path string true "Image ID" example(pwnrxtbi9z0v)
+// @Success 200
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/gallery/{imageId} [delete]
+func (s *EventsService) DeleteGalleryImage(c *gin.Context) {
+	eventId := c.Param("eventId")
+
+	if !s.validateUserCanManageEvent(c, eventId) {
+		return
+	}
+
+	deleted, err := s.deleteImages("event_id = $1 AND kind = $2 AND public_id = $3", eventId, imageKindGallery, c.Param("imageId"))
+	if err != nil {
+		log.Println("(DeleteGalleryImage) deleteImages", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error deleting image"))
+
+		return
+	}
+
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, utils.GetError("Image not found"))
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,436 @@
+package events
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+const (
+	maxImportRows  = 500
+	maxImportBytes = 2 << 20
+	// importListSeparator splits exceptionDates and tags inside a CSV cell
+	importListSeparator = ";"
+)
+
+// importLocalLayouts are accepted for times without an offset, which
+// spreadsheets usually export. They are read in the timezone of the row.
+var importLocalLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+type importRow struct {
+	row    int
+	input  models.EventInput
+	errors validationErrors
+}
+
+func parseImportTime(value string, timezone string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+
+	zone, err := utils.LoadTimezone(timezone)
+	if err != nil {
+		zone = location(utils.DefaultTimezone)
+	}
+
+	for _, layout := range importLocalLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, zone); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, errors.New("invalid time")
+}
+
+func splitImportList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, importListSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// setImportField stores a CSV cell in the input, the cell is not empty.
+func setImportField(input *models.EventInput, field string, value string, errs *validationErrors) {
+	parseNumber := func(bits int) (uint64, bool) {
+		number, err := strconv.ParseUint(value, 10, bits)
+		if err != nil {
+			errs.add(field, field+" must be a non-negative integer")
+
+			return 0, false
+		}
+
+		return number, true
+	}
+
+	parseFloat := func() *float64 {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			errs.add(field, field+" must be a number")
+
+			return nil
+		}
+
+		return &number
+	}
+
+	switch field {
+	case "name":
+		input.Name = value
+	case "sport":
+		input.Sport = value
+	case "location":
+		input.Location = value
+	case "venueId":
+		input.VenueID = &value
+	case "description":
+		input.Description = value
+	case "level":
+		input.Level = value
+	case "timezone":
+		input.Timezone = value
+	case "status":
+		input.Status = value
+	case "recurrenceRule":
+		input.RecurrenceRule = &value
+	case "exceptionDates":
+		input.ExceptionDates = splitImportList(value)
+	case "tags":
+		input.Tags = splitImportList(value)
+	case "latitude":
+		input.Latitude = parseFloat()
+	case "longitude":
+		input.Longitude = parseFloat()
+	case "price":
+		if number, ok := parseNumber(16); ok {
+			input.Price = uint16(number)
+		}
+	case "capacity":
+		if number, ok := parseNumber(16); ok {
+			capacity := uint16(number)
+			input.Capacity = &capacity
+		}
+	}
+}
+
+var importTimeFields = map[string]bool{"startsAt": true, "endsAt": true}
+
+var importFields = map[string]bool{
+	"name": true, "sport": true, "startsAt": true, "endsAt": true, "timezone": true, "location": true, "venueId": true,
+	"latitude": true, "longitude": true, "price": true, "description": true, "level": true, "capacity": true,
+	"recurrenceRule": true, "exceptionDates": true, "tags": true, "status": true,
+}
+
+// parseImportCSV reads a CSV with a header row naming the EventInput fields.
+func parseImportCSV(data []byte) ([]importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("CSV must start with a header row")
+	}
+
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if !importFields[name] {
+			return nil, errors.New("Unknown CSV column " + name)
+		}
+		header[i] = name
+	}
+
+	rows := []importRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("Invalid CSV: " + err.Error())
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := importRow{row: line, errors: validationErrors{}}
+		cells := map[string]string{}
+		for i, value := range record {
+			if value = strings.TrimSpace(value); value != "" {
+				cells[header[i]] = value
+			}
+		}
+
+		for field, value := range cells {
+			if !importTimeFields[field] {
+				setImportField(&row.input, field, value, &row.errors)
+			}
+		}
+
+		// times without an offset depend on the timezone column
+		for field := range importTimeFields {
+			value, ok := cells[field]
+			if !ok {
+				continue
+			}
+
+			parsed, err := parseImportTime(value, row.input.Timezone)
+			if err != nil {
+				row.errors.add(field, field+" must be an RFC 3339 time or YYYY-MM-DD HH:MM")
+
+				continue
+			}
+
+			if field == "startsAt" {
+				row.input.StartsAt = parsed
+			} else {
+				row.input.EndsAt = &parsed
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseImportJSON reads a JSON array of EventInput objects.
+func parseImportJSON(data []byte) ([]importRow, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, errors.New("JSON body must be an array of events")
+	}
+
+	rows := []importRow{}
+	for i, item := range items {
+		row := importRow{row: i + 1, errors: validationErrors{}}
+		if err := json.Unmarshal(item, &row.input); err != nil {
+			row.errors.add("", "Invalid event: "+err.Error())
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// readImport returns the uploaded file or the raw body together with its
+// format, csv or json.
+func readImport(c *gin.Context) ([]byte, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			return nil, "", errors.New("Missing file")
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, "", errors.New("File is too large")
+		}
+
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".csv":
+			return data, "csv", nil
+		case ".json":
+			return data, "json", nil
+		}
+
+		return nil, "", errors.New("File must be a .csv or .json file")
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, "", errors.New("Request body is too large")
+	}
+
+	switch mediaType {
+	case "text/csv":
+		return data, "csv", nil
+	case "application/json":
+		return data, "json", nil
+	}
+
+	return nil, "", errors.New("Content-Type must be text/csv, application/json or multipart/form-data")
+}
+
+// validateImportRow runs the checks of createEvent on a single row.
+func (s *EventsService) validateImportRow(row *importRow, references *eventReferences) (*recurrence, string, error) {
+	fields, err := s.validateEventInput(&row.input, nil, references)
+	if err != nil {
+		return nil, "", err
+	}
+	row.errors = append(row.errors, fields...)
+
+	status, ok := validateInitialStatus(row.input.Status)
+	if !ok {
+		row.errors.add("status", "status must be draft or published")
+	}
+
+	if len(row.errors) > 0 {
+		return nil, "", nil
+	}
+
+	series, err := parseRecurrenceInput(localDate(row.input.StartsAt, location(row.input.Timezone)), row.input.RecurrenceRule, row.input.ExceptionDates)
+	if err != nil {
+		row.errors.add("recurrenceRule", err.Error())
+
+		return nil, "", nil
+	}
+
+	return series, status, nil
+}
+
+// @Summary Import events
+// @Description Creates events from a CSV file or a JSON array of events. The CSV header names the event fields, exceptionDates and tags are separated by semicolons and times without an offset are read in the timezone of the row. Every row is validated, valid rows are created in one transaction and rejected rows are reported with their errors. With dryRun nothing is created.
+// @Tags events
+// @Accept text/csv
+// @Accept json
+// @Accept multipart/form-data
+// @Produce json
+// @Param dryRun query bool false "Only validate the rows" default(false)
+// @Param file formData file false "CSV or JSON file, when sent as multipart/form-data"
+// @Success 200 {object} models.EventImportResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/import [post]
+func (s *EventsService) ImportEvents(c *gin.Context) {
+	dryRun, err := parseBoolParam(c, "dryRun")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
+	}
+
+	data, format, err := readImport(c)
+	if err != nil {
+		log.Println("(ImportEvents) readImport", err)
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
+	}
+
+	var rows []importRow
+	if format == "csv" {
+		rows, err = parseImportCSV(data)
+	} else {
+		rows, err = parseImportJSON(data)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
+	}
+
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, utils.GetError("No events to import"))
+
+		return
+	}
+
+	if len(rows) > maxImportRows {
+		c.JSON(http.StatusBadRequest, utils.GetError("At most "+strconv.Itoa(maxImportRows)+" events can be imported at once"))
+
+		return
+	}
+
+	userID := c.GetString(constants.UserID_key)
+
+	references, err := s.loadReferences()
+	if err != nil {
+		log.Println("(ImportEvents) loadReferences", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error importing events"))
+
+		return
+	}
+
+	// every row is validated before the transaction starts, so it does not
+	// hold a connection while the venues are looked up
+	series := make([]*recurrence, len(rows))
+	statuses := make([]string, len(rows))
+	result := models.EventImportResult{DryRun: dryRun, Rows: make([]models.EventImportRow, len(rows))}
+	for i := range rows {
+		row := &rows[i]
+		result.Rows[i].Row = row.row
+
+		if len(row.errors) == 0 {
+			series[i], statuses[i], err = s.validateImportRow(row, references)
+			if err != nil {
+				log.Println("(ImportEvents) validateImportRow", err)
+				c.JSON(http.StatusInternalServerError, utils.GetError("Error importing events"))
+
+				return
+			}
+		}
+
+		if len(row.errors) > 0 {
+			result.Rows[i].Errors = row.errors
+			result.Rejected++
+		} else {
+			result.Created++
+		}
+	}
+
+	if dryRun || result.Created == 0 {
+		c.JSON(http.StatusOK, result)
+
+		return
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Println("(ImportEvents) db.Begin", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error importing events"))
+
+		return
+	}
+	defer tx.Rollback()
+
+	for i := range rows {
+		row := &rows[i]
+		if len(row.errors) > 0 {
+			continue
+		}
+
+		newEvent, err := buildEvent(row.input, userID, series[i], statuses[i])
+		if err == nil {
+			err = insertEvent(tx, newEvent, series[i])
+		}
+		if err != nil {
+			log.Println("(ImportEvents) insertEvent", row.row, err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error importing row "+strconv.Itoa(row.row)+", no events were created"))
+
+			return
+		}
+
+		result.Rows[i].EventID = &newEvent.Public_ID
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("(ImportEvents) tx.Commit", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error importing events"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/models"
+)
+
+func ownedScope(userID string) eventScope {
+	return eventScope{
+		personal: true,
+		condition: func(q *eventQuery) string {
+			return "events.owner_id = " + q.arg(userID)
+		},
+	}
+}
+
+// @Summary Get my events
+// @Description Lists events owned by the current user, drafts included. Accepts the same filters, sorting and pagination as GET /events, with period instead of includePast.
+// @Tags events
+// @Produce json
+// @Param period query string false "Upcoming events, past events or both" Enums(all, upcoming, past) default(all)
+// @Param status query string false "Event status" Enums(draft, published, cancelled, completed)
+// @Param page query int false "Page number, ignored in cursor mode" default(1)
+// @Param limit query int false "Number of events per page" default(12)
+// @Param cursor query string false "Opaque cursor from nextCursor or the Link header"
+// @Param withTotal query bool false "Count all matching events, returned in X-Total-Count and in the envelope"
+// @Param envelope query bool false "Wrap the events in an object with data, total and nextCursor"
+// @Param sort query string false "Sort order, a leading minus means descending" Enums(date, -date, price, -price, createdAt, -createdAt, distance)
+// @Success 200 {array} models.EventWithOwner "Plain array, or models.EventPage when envelope=true"
+// @Header 200 {string} Link "RFC 8288 links to the first, previous, next and last page"
+// @Header 200 {integer} X-Total-Count "Number of matching events, only with withTotal=true"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /user/me/events [get]
+func (s *EventsService) GetMyEvents(c *gin.Context) {
+	userID := c.GetString(constants.UserID_key)
+
+	s.listEvents(c, ownedScope(userID))
+}
+
+// @Summary Get joined events
+// @Description Lists events the current user was approved to join through an email request. Accepts the same filters, sorting and pagination as GET /events, with period instead of includePast.
+// @Tags events
+// @Produce json
+// @Param period query string false "Upcoming events, past events or both" Enums(all, upcoming, past) default(all)
+// @Param status query string false "Event status" Enums(published, cancelled, completed)
+// @Param page query int false "Page number, ignored in cursor mode" default(1)
+// @Param limit query int false "Number of events per page" default(12)
+// @Param cursor query string false "Opaque cursor from nextCursor or the Link header"
+// @Param withTotal query bool false "Count all matching events, returned in X-Total-Count and in the envelope"
+// @Param envelope query bool false "Wrap the events in an object with data, total and nextCursor"
+// @Param sort query string false "Sort order, a leading minus means descending" Enums(date, -date, price, -price, createdAt, -createdAt, distance)
+// @Success 200 {array} models.EventWithOwner "Plain array, or models.EventPage when envelope=true"
+// @Header 200 {string} Link "RFC 8288 links to the first, previous, next and last page"
+// @Header 200 {integer} X-Total-Count "Number of matching events, only with withTotal=true"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /user/me/joined-events [get]
+func (s *EventsService) GetJoinedEvents(c *gin.Context) {
+	userID := c.GetString(constants.UserID_key)
+
+	// drafts stay hidden, an event could only be joined once published
+	s.listEvents(c, eventScope{
+		personal: true,
+		condition: func(q *eventQuery) string {
+			return "events.status != " + q.arg(models.EventStatusDraft) +
+				" AND EXISTS (SELECT 1 FROM email_requests WHERE email_requests.event_id = events.public_id AND email_requests.requester_id = " + q.arg(userID) + " AND email_requests.approved = true)"
+		},
+	})
+}
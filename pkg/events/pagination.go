@@ -0,0 +1,171 @@
+package events
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/models"
+)
+
+const cursorTimestampLayout = "2006-01-02 15:04:05.999999"
+
+// eventCursor points at the last event of a page. It is handed to clients as
+// an opaque base64 string, so its content can change without breaking them.
+type eventCursor struct {
+	Sort  string  `json:"s"`
+	Value *string `json:"v"`
+	ID    int     `json:"i"`
+}
+
+func encodeCursor(event models.EventWithOwner, sort string) string {
+	data, _ := json.Marshal(eventCursor{Sort: sort, Value: sortOptions[sort].value(event), ID: event.ID})
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*eventCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor eventCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+type pagination struct {
+	Page       int
+	Limit      int
+	CursorMode bool
+	Cursor     *eventCursor
+	WithTotal  bool
+	Envelope   bool
+}
+
+func parseBoolParam(c *gin.Context, name string) (bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return false, nil
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("Invalid " + name + " parameter, expected true or false")
+	}
+
+	return result, nil
+}
+
+func parsePagination(c *gin.Context) (*pagination, error) {
+	var err error
+	p := &pagination{}
+
+	p.Page, err = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || p.Page < 1 {
+		return nil, errors.New("Invalid page parameter")
+	}
+
+	p.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "12"))
+	if err != nil || p.Limit < 1 {
+		return nil, errors.New("Invalid limit parameter")
+	}
+
+	// an empty cursor asks for the first page in cursor mode
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		p.CursorMode = true
+
+		if _, hasPage := c.GetQuery("page"); hasPage {
+			return nil, errors.New("cursor cannot be combined with page")
+		}
+
+		if cursor != "" {
+			if p.Cursor, err = decodeCursor(cursor); err != nil {
+				return nil, errors.New("Invalid cursor parameter")
+			}
+		}
+	}
+
+	if p.WithTotal, err = parseBoolParam(c, "withTotal"); err != nil {
+		return nil, err
+	}
+
+	if p.Envelope, err = parseBoolParam(c, "envelope"); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *pagination) apply(q *eventQuery, sort string) {
+	if !p.CursorMode {
+		q.limit = p.Limit
+		q.offset = (p.Page - 1) * p.Limit
+
+		return
+	}
+
+	if p.Cursor != nil {
+		applyCursor(q, sort, p.Cursor)
+	}
+
+	// one extra row tells whether there is a next page
+	q.limit = p.Limit + 1
+}
+
+func pageURL(c *gin.Context, changes map[string]string) string {
+	values := c.Request.URL.Query()
+	for key, value := range changes {
+		if value == "" && key != "cursor" {
+			values.Del(key)
+
+			continue
+		}
+
+		values.Set(key, value)
+	}
+
+	u := url.URL{Path: c.Request.URL.Path, RawQuery: values.Encode()}
+
+	return u.String()
+}
+
+// setLinkHeader writes RFC 8288 links to the neighbouring pages.
+func setLinkHeader(c *gin.Context, p *pagination, hasNext bool, nextCursor string, total *int) {
+	links := []string{}
+	addLink := func(rel string, changes map[string]string) {
+		links = append(links, "<"+pageURL(c, changes)+`>; rel="`+rel+`"`)
+	}
+
+	if p.CursorMode {
+		addLink("first", map[string]string{"cursor": ""})
+		if hasNext {
+			addLink("next", map[string]string{"cursor": nextCursor})
+		}
+	} else {
+		addLink("first", map[string]string{"page": "1"})
+		if p.Page > 1 {
+			addLink("prev", map[string]string{"page": strconv.Itoa(p.Page - 1)})
+		}
+		if hasNext {
+			addLink("next", map[string]string{"page": strconv.Itoa(p.Page + 1)})
+		}
+		if total != nil {
+			lastPage := (*total + p.Limit - 1) / p.Limit
+			if lastPage < 1 {
+				lastPage = 1
+			}
+			addLink("last", map[string]string{"page": strconv.Itoa(lastPage)})
+		}
+	}
+
+	c.Header("Link", strings.Join(links, ", "))
+}
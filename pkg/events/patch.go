@@ -0,0 +1,150 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/utils"
+	"github.com/lib/pq"
+)
+
+func (s *EventsService) getEventInput(eventId string) (*models.EventInput, error) {
+	var input models.EventInput
+	query := "SELECT name, sport, starts_at, ends_at, timezone, location, venue_id, latitude, longitude, price, description, level, capacity, recurrence_rule, recurrence_exdates, " +
+		tagsColumn + " FROM events WHERE public_id = $1 AND deleted_at IS NULL"
+	err := s.db.QueryRow(query, eventId).Scan(&input.Name, &input.Sport, &input.StartsAt, &input.EndsAt, &input.Timezone, &input.Location, &input.VenueID, &input.Latitude, &input.Longitude,
+		&input.Price, &input.Description, &input.Level, &input.Capacity, &input.RecurrenceRule, pq.Array(&input.ExceptionDates), pq.Array(&input.Tags))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, date := range input.ExceptionDates {
+		if len(date) > len(dateLayout) {
+			input.ExceptionDates[i] = date[:len(dateLayout)]
+		}
+	}
+
+	return &input, nil
+}
+
+// applyMergePatch merges the patch into the current event input. Unknown
+// members are rejected so a typo does not turn into a silent no-op.
+func applyMergePatch(current *models.EventInput, patch map[string]interface{}) (*models.EventInput, error) {
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(currentJSON, &document); err != nil {
+		return nil, err
+	}
+
+	mergedJSON, err := json.Marshal(utils.MergePatch(document, patch))
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(mergedJSON))
+	decoder.DisallowUnknownFields()
+
+	var merged models.EventInput
+	if err := decoder.Decode(&merged); err != nil {
+		return nil, err
+	}
+
+	return &merged, nil
+}
+
+// @Summary Partially update an event
+// @Description Applies a JSON Merge Patch (RFC 7396) to the event: only the provided fields change and null removes optional values. The merged event is validated as a whole.
+// @Tags events
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param patch body models.EventInput true "Fields to change"
+// @Success 200 {object} models.Event
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid event, fields lists the problems"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId} [patch]
+func (s *EventsService) PatchEvent(c *gin.Context) {
+	var patch map[string]interface{}
+	if err := json.NewDecoder(c.Request.Body).Decode(&patch); err != nil || patch == nil {
+		log.Println("(PatchEvent) json.Decode", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Request body must be a JSON object"))
+
+		return
+	}
+
+	if _, ok := patch["status"]; ok {
+		c.JSON(http.StatusBadRequest, utils.GetError("status is changed only through the publish, cancel and complete endpoints"))
+
+		return
+	}
+
+	eventId := c.Param("eventId")
+
+	if !s.validateUserCanManageEvent(c, eventId) {
+		return
+	}
+
+	current, err := s.getEventInput(eventId)
+	if err != nil {
+		log.Println("(PatchEvent) getEventInput", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error updating event"))
+
+		return
+	}
+
+	updates, err := applyMergePatch(current, patch)
+	if err != nil {
+		log.Println("(PatchEvent) applyMergePatch", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid patch: "+err.Error()))
+
+		return
+	}
+
+	if !s.validateEvent(c, updates, &current.StartsAt) {
+		return
+	}
+
+	series, err := parseRecurrenceInput(localDate(updates.StartsAt, location(updates.Timezone)), updates.RecurrenceRule, updates.ExceptionDates)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
+	}
+
+	err = s.updateEventRow(eventId, *updates, series)
+	if err == errCapacityBelowParticipants {
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
+	}
+	if err != nil {
+		log.Println("(PatchEvent) updateEventRow", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Error updating event"))
+
+		return
+	}
+
+	var event models.EventWithOwner
+	err = s.db.QueryRow("SELECT "+columns+" FROM events WHERE public_id = $1 AND deleted_at IS NULL", eventId).Scan(getColumnForEvent(&event)...)
+	if err != nil {
+		log.Println("(PatchEvent) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error reading updated event"))
+
+		return
+	}
+	presentEvent(&event.Event)
+
+	c.JSON(http.StatusOK, event.Event)
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"strconv"
+	"strings"
+)
+
+// eventQuery builds a parameterized SELECT over the events table. Every user
+// supplied value goes through arg so it ends up as a $n placeholder and never
+// gets concatenated into the SQL itself.
+type eventQuery struct {
+	distance   string
+	rank       string
+	conditions []string
+	orderBy    string
+	limit      int
+	offset     int
+	args       []interface{}
+}
+
+func newEventQuery() *eventQuery {
+	// soft deleted events never show up in listings
+	return &eventQuery{orderBy: "events.created_at DESC, events.id DESC", conditions: []string{"events.deleted_at IS NULL"}}
+}
+
+func (q *eventQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+func (q *eventQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+func (q *eventQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// build returns the page query. It leaves the query untouched, so the same
+// conditions can be reused by buildCount.
+func (q *eventQuery) build() (string, []interface{}) {
+	distance := q.distance
+	if distance == "" {
+		distance = "NULL::double precision"
+	}
+
+	args := append([]interface{}{}, q.args...)
+	query := "SELECT " + columns + ", " + distance + " AS distance_km FROM events" + q.whereClause() + " ORDER BY " + q.orderBy
+
+	if q.limit > 0 {
+		args = append(args, q.limit)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+
+	if q.offset > 0 {
+		args = append(args, q.offset)
+		query += " OFFSET $" + strconv.Itoa(len(args))
+	}
+
+	return query, args
+}
+
+func (q *eventQuery) buildCount() (string, []interface{}) {
+	return "SELECT COUNT(*) FROM events" + q.whereClause(), q.args
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,427 @@
+package events
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/utils"
+	"github.com/lib/pq"
+)
+
+const maxExpandWindowDays = 366
+
+type recurrence struct {
+	Rule    *string
+	Exdates []string
+	End     *time.Time
+}
+
+func parseRecurrenceInput(date time.Time, rule *string, exceptionDates []string) (*recurrence, error) {
+	result := &recurrence{Exdates: []string{}}
+
+	if rule == nil || strings.TrimSpace(*rule) == "" {
+		if len(exceptionDates) > 0 {
+			return nil, errors.New("exceptionDates require a recurrenceRule")
+		}
+
+		return result, nil
+	}
+
+	if date.IsZero() {
+		return nil, errors.New("recurring events require a date of the first occurrence")
+	}
+
+	parsed, err := utils.ParseRecurrenceRule(*rule)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(*rule), "RRULE:"))
+	result.Rule = &normalized
+	result.End = parsed.End(date)
+
+	for _, exceptionDate := range exceptionDates {
+		parsedDate, err := time.Parse(dateLayout, exceptionDate)
+		if err != nil {
+			return nil, errors.New("Invalid exception date " + exceptionDate + ", expected YYYY-MM-DD")
+		}
+
+		result.Exdates = append(result.Exdates, parsedDate.Format(dateLayout))
+	}
+
+	return result, nil
+}
+
+func parseExdates(values []string) []time.Time {
+	dates := []time.Time{}
+	for _, value := range values {
+		// Postgres may hand the dates back as full timestamps
+		if len(value) > len(dateLayout) {
+			value = value[:len(dateLayout)]
+		}
+
+		date, err := time.Parse(dateLayout, value)
+		if err != nil {
+			log.Println("(parseExdates) time.Parse", err)
+
+			continue
+		}
+
+		dates = append(dates, date)
+	}
+
+	return dates
+}
+
+type occurrenceOverride struct {
+	Name        *string
+	StartsAt    *time.Time
+	EndsAt      *time.Time
+	Location    *string
+	Price       *uint16
+	Description *string
+}
+
+func (s *EventsService) getOccurrenceOverrides(eventIds []string, from, to time.Time) (map[string]map[string]occurrenceOverride, error) {
+	overrides := map[string]map[string]occurrenceOverride{}
+	if len(eventIds) == 0 {
+		return overrides, nil
+	}
+
+	query := `
+		SELECT event_id, occurrence_date, name, starts_at, ends_at, location, price, description
+		FROM event_occurrences
+		WHERE event_id = ANY($1) AND occurrence_date BETWEEN $2 AND $3
+	`
+	rows, err := s.db.Query(query, pq.Array(eventIds), from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventId string
+		var occurrenceDate time.Time
+		var override occurrenceOverride
+		if err := rows.Scan(&eventId, &occurrenceDate, &override.Name, &override.StartsAt, &override.EndsAt, &override.Location, &override.Price, &override.Description); err != nil {
+			return nil, err
+		}
+
+		if overrides[eventId] == nil {
+			overrides[eventId] = map[string]occurrenceOverride{}
+		}
+		overrides[eventId][occurrenceDate.Format(dateLayout)] = override
+	}
+
+	return overrides, rows.Err()
+}
+
+// expandOccurrences replaces every recurring event with its occurrences in
+// [from, to], applying per-occurrence edits, and sorts the result by start.
+// Occurrences keep the local start time and the duration of the series.
+func (s *EventsService) expandOccurrences(events []models.EventWithOwner, from, to time.Time) ([]models.EventWithOwner, error) {
+	recurringIds := []string{}
+	for _, event := range events {
+		if event.RecurrenceRule != nil {
+			recurringIds = append(recurringIds, event.Public_ID)
+		}
+	}
+
+	overrides, err := s.getOccurrenceOverrides(recurringIds, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := []models.EventWithOwner{}
+	for _, event := range events {
+		if event.RecurrenceRule == nil {
+			date := localDate(event.StartsAt, location(event.Timezone))
+			if !date.Before(from) && !date.After(to) {
+				expanded = append(expanded, event)
+			}
+
+			continue
+		}
+
+		rule, err := utils.ParseRecurrenceRule(*event.RecurrenceRule)
+		if err != nil {
+			log.Println("(expandOccurrences) utils.ParseRecurrenceRule", event.Public_ID, err)
+
+			continue
+		}
+
+		start := localDate(event.StartsAt, location(event.Timezone))
+		for _, date := range rule.Occurrences(start, from, to, parseExdates(event.ExceptionDates)) {
+			occurrence := event
+			occurrenceDate := date.Format(dateLayout)
+			occurrence.OccurrenceDate = &occurrenceDate
+			occurrence.StartsAt, occurrence.EndsAt = occurrenceTimes(event.Event, date)
+
+			if override, ok := overrides[event.Public_ID][occurrenceDate]; ok {
+				applyOccurrenceOverride(&occurrence, override)
+			}
+
+			expanded = append(expanded, occurrence)
+		}
+	}
+
+	sort.SliceStable(expanded, func(i, j int) bool {
+		return expanded[i].StartsAt.Before(expanded[j].StartsAt)
+	})
+
+	return expanded, nil
+}
+
+// overrideTimes applies a changed start and end to an occurrence. A new start
+// without a new end moves the occurrence keeping its duration.
+func overrideTimes(startsAt time.Time, endsAt *time.Time, newStartsAt *time.Time, newEndsAt *time.Time) (time.Time, *time.Time) {
+	if newStartsAt != nil {
+		if endsAt != nil && newEndsAt == nil {
+			moved := newStartsAt.Add(endsAt.Sub(startsAt))
+			endsAt = &moved
+		}
+
+		startsAt = *newStartsAt
+	}
+
+	if newEndsAt != nil {
+		endsAt = newEndsAt
+	}
+
+	return startsAt, endsAt
+}
+
+func applyOccurrenceOverride(event *models.EventWithOwner, override occurrenceOverride) {
+	if override.Name != nil {
+		event.Name = *override.Name
+	}
+
+	if override.StartsAt != nil || override.EndsAt != nil {
+		event.StartsAt, event.EndsAt = overrideTimes(event.StartsAt, event.EndsAt, override.StartsAt, override.EndsAt)
+	}
+
+	if override.Location != nil {
+		event.Location = *override.Location
+	}
+
+	if override.Price != nil {
+		event.Price = *override.Price
+	}
+
+	if override.Description != nil {
+		event.Description = *override.Description
+	}
+
+	presentEvent(&event.Event)
+}
+
+// findOccurrence checks that the date is a scheduled, not cancelled occurrence
+// of the recurring event and writes the error response if it is not. The
+// returned event carries the series start and end moved to that date.
+func (s *EventsService) findOccurrence(c *gin.Context, eventId string, occurrenceDate string) (time.Time, *models.Event, bool) {
+	date, err := time.Parse(dateLayout, occurrenceDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid occurrence date, expected YYYY-MM-DD"))
+
+		return time.Time{}, nil, false
+	}
+
+	var event models.Event
+	var rule *string
+	var exdates []string
+	err = s.db.QueryRow("SELECT starts_at, ends_at, timezone, recurrence_rule, recurrence_exdates FROM events WHERE public_id = $1 AND deleted_at IS NULL", eventId).
+		Scan(&event.StartsAt, &event.EndsAt, &event.Timezone, &rule, pq.Array(&exdates))
+	if err != nil {
+		log.Println("(findOccurrence) db.QueryRow", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+		return time.Time{}, nil, false
+	}
+
+	if rule == nil {
+		c.JSON(http.StatusBadRequest, utils.GetError("Event is not recurring"))
+
+		return time.Time{}, nil, false
+	}
+
+	parsed, err := utils.ParseRecurrenceRule(*rule)
+	if err != nil {
+		log.Println("(findOccurrence) utils.ParseRecurrenceRule", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error reading recurrence rule"))
+
+		return time.Time{}, nil, false
+	}
+
+	start := localDate(event.StartsAt, location(event.Timezone))
+	if len(parsed.Occurrences(start, date, date, parseExdates(exdates))) == 0 {
+		c.JSON(http.StatusNotFound, utils.GetError("Occurrence not found"))
+
+		return time.Time{}, nil, false
+	}
+
+	event.StartsAt, event.EndsAt = occurrenceTimes(event, date)
+
+	return date, &event, true
+}
+
+// validateOccurrenceInput checks the overridden fields with the limits of
+// events and sanitizes the description.
+func validateOccurrenceInput(input *models.OccurrenceInput) validationErrors {
+	errs := validationErrors{}
+
+	if input.Name != nil {
+		errs.text("name", input.Name, maxNameLength)
+	}
+
+	if input.Location != nil {
+		errs.text("location", input.Location, maxLocationLength)
+	}
+
+	if input.Price != nil && *input.Price > maxPrice {
+		errs.add("price", "price must be at most "+strconv.Itoa(maxPrice))
+	}
+
+	if input.Description != nil {
+		errs.description(input.Description)
+	}
+
+	return errs
+}
+
+// @Summary Update a single occurrence
+// @Description Changes one occurrence of a recurring event, the rest of the series stays untouched. Use PUT /events/{eventId} to update the whole series.
+// @Tags events
+// @Accept json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param occurrenceDate path string true "Original date of the occurrence, YYYY-MM-DD" example(2024-01-09)
+// @Param occurrence body models.OccurrenceInput true "Fields to change for this occurrence"
+// @Success 200
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid occurrence, fields lists the problems"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/occurrences/{occurrenceDate} [put]
+func (s *EventsService) UpdateOccurrence(c *gin.Context) {
+	var input models.OccurrenceInput
+	if err := c.BindJSON(&input); err != nil {
+		log.Println("(UpdateOccurrence) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Error while parsing request body"))
+
+		return
+	}
+
+	eventId := c.Param("eventId")
+
+	if !s.validateUserCanManageEvent(c, eventId) {
+		return
+	}
+
+	occurrenceDate, occurrence, ok := s.findOccurrence(c, eventId, c.Param("occurrenceDate"))
+	if !ok {
+		return
+	}
+
+	errs := validateOccurrenceInput(&input)
+
+	startsAt, endsAt := overrideTimes(occurrence.StartsAt, occurrence.EndsAt, input.StartsAt, input.EndsAt)
+	if endsAt != nil && !endsAt.After(startsAt) {
+		errs.add("endsAt", "endsAt must be after startsAt")
+	}
+
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, utils.GetValidationError("Invalid occurrence", errs))
+
+		return
+	}
+
+	query := `
+		INSERT INTO event_occurrences (event_id, occurrence_date, name, starts_at, ends_at, location, price, description, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (event_id, occurrence_date) DO UPDATE SET
+			name = EXCLUDED.name,
+			starts_at = EXCLUDED.starts_at,
+			ends_at = EXCLUDED.ends_at,
+			location = EXCLUDED.location,
+			price = EXCLUDED.price,
+			description = EXCLUDED.description,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := s.db.Exec(query, eventId, occurrenceDate, input.Name, input.StartsAt, input.EndsAt, input.Location, input.Price, input.Description, time.Now())
+	if err != nil {
+		log.Println("(UpdateOccurrence) db.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error updating occurrence"))
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Cancel a single occurrence
+// @Description Cancels one occurrence of a recurring event by adding it to the exception dates. Use DELETE /events/{eventId} to remove the whole series.
+// @Tags events
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param occurrenceDate path string true "Original date of the occurrence, YYYY-MM-DD" example(2024-01-09)
+// @Success 200
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/occurrences/{occurrenceDate} [delete]
+func (s *EventsService) CancelOccurrence(c *gin.Context) {
+	eventId := c.Param("eventId")
+
+	if !s.validateUserCanManageEvent(c, eventId) {
+		return
+	}
+
+	occurrenceDate, _, ok := s.findOccurrence(c, eventId, c.Param("occurrenceDate"))
+	if !ok {
+		return
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Println("(CancelOccurrence) db.Begin", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error cancelling occurrence"))
+
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE events SET recurrence_exdates = array_append(recurrence_exdates, $1::date) WHERE public_id = $2 AND deleted_at IS NULL", occurrenceDate, eventId)
+	if err != nil {
+		log.Println("(CancelOccurrence) tx.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error cancelling occurrence"))
+
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM event_occurrences WHERE event_id = $1 AND occurrence_date = $2", eventId, occurrenceDate)
+	if err != nil {
+		log.Println("(CancelOccurrence) tx.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error cancelling occurrence"))
+
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("(CancelOccurrence) tx.Commit", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error cancelling occurrence"))
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
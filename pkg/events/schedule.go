@@ -0,0 +1,76 @@
+package events
+
+import (
+	"time"
+
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+// validateSchedule checks the start, end and timezone of the input and fills
+// in the default timezone. A new start has to be in the future, previous is
+// the stored start of an existing event, which may stay in the past.
+func validateSchedule(input *models.EventInput, previous *time.Time, errs *validationErrors) {
+	if input.Timezone == "" {
+		input.Timezone = utils.DefaultTimezone
+	}
+
+	if _, err := utils.LoadTimezone(input.Timezone); err != nil {
+		errs.add("timezone", "timezone must be an IANA timezone such as Europe/Prague")
+	}
+
+	if input.StartsAt.IsZero() {
+		errs.add("startsAt", "startsAt is required")
+
+		return
+	}
+
+	changed := previous == nil || !previous.Equal(input.StartsAt)
+	if changed && !input.StartsAt.After(time.Now()) {
+		errs.add("startsAt", "startsAt must be in the future")
+	}
+
+	if input.EndsAt != nil && !input.EndsAt.After(input.StartsAt) {
+		errs.add("endsAt", "endsAt must be after startsAt")
+	}
+}
+
+// localDate returns the calendar day of t in the location at UTC midnight,
+// the form used by DATE columns and recurrence rules.
+func localDate(t time.Time, location *time.Location) time.Time {
+	return utils.TruncateToDate(t.In(location))
+}
+
+// localizeEvent renders the times of the event in its own timezone, so
+// clients see the local time the organizer entered.
+func localizeEvent(event *models.Event) {
+	event.StartsAt = utils.InTimezone(event.StartsAt, event.Timezone)
+	if event.EndsAt != nil {
+		endsAt := utils.InTimezone(*event.EndsAt, event.Timezone)
+		event.EndsAt = &endsAt
+	}
+}
+
+// occurrenceTimes returns the start and end of the series occurrence on the
+// given day, keeping the local time of day and the duration of the event.
+func occurrenceTimes(event models.Event, day time.Time) (time.Time, *time.Time) {
+	startsAt := utils.OnDay(utils.InTimezone(event.StartsAt, event.Timezone), day)
+	if event.EndsAt == nil {
+		return startsAt, nil
+	}
+
+	endsAt := startsAt.Add(event.EndsAt.Sub(event.StartsAt))
+
+	return startsAt, &endsAt
+}
+
+// location loads the timezone of a stored event, falling back to UTC for
+// names the server does not know.
+func location(name string) *time.Location {
+	loaded, err := utils.LoadTimezone(name)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loaded
+}
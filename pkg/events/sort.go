@@ -0,0 +1,112 @@
+package events
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/globus303/sportujspolu/models"
+)
+
+const (
+	sortRelevance = "relevance"
+	sortNewest    = "-createdAt"
+	sortDistance  = "distance"
+)
+
+type sortOption struct {
+	expression func(q *eventQuery) string
+	descending bool
+	// cast turns the cursor value, always sent as text, into the column type
+	cast string
+	// value returns the sort key of the event for the cursor, nil for NULL
+	value func(event models.EventWithOwner) *string
+}
+
+func column(expression string) func(q *eventQuery) string {
+	return func(q *eventQuery) string { return expression }
+}
+
+func eventStartsAtValue(event models.EventWithOwner) *string {
+	if event.StartsAt.IsZero() {
+		return nil
+	}
+
+	value := event.StartsAt.UTC().Format(time.RFC3339Nano)
+
+	return &value
+}
+
+func eventPriceValue(event models.EventWithOwner) *string {
+	value := strconv.Itoa(int(event.Price))
+
+	return &value
+}
+
+func eventCreatedAtValue(event models.EventWithOwner) *string {
+	value := event.Created_At.Format(cursorTimestampLayout)
+
+	return &value
+}
+
+func eventDistanceValue(event models.EventWithOwner) *string {
+	if event.DistanceKm == nil {
+		return nil
+	}
+
+	value := strconv.FormatFloat(*event.DistanceKm, 'g', -1, 64)
+
+	return &value
+}
+
+var sortOptions = map[string]sortOption{
+	"date":       {expression: column("events.starts_at"), cast: "timestamptz", value: eventStartsAtValue},
+	"-date":      {expression: column("events.starts_at"), descending: true, cast: "timestamptz", value: eventStartsAtValue},
+	"price":      {expression: column("events.price"), cast: "int", value: eventPriceValue},
+	"-price":     {expression: column("events.price"), descending: true, cast: "int", value: eventPriceValue},
+	"createdAt":  {expression: column("events.created_at"), cast: "timestamp", value: eventCreatedAtValue},
+	"-createdAt": {expression: column("events.created_at"), descending: true, cast: "timestamp", value: eventCreatedAtValue},
+	sortDistance: {expression: func(q *eventQuery) string { return q.distance }, cast: "double precision", value: eventDistanceValue},
+}
+
+func sortDirection(descending bool) string {
+	if descending {
+		return "DESC"
+	}
+
+	return "ASC"
+}
+
+// applySort orders by the sort key with NULLs last and breaks ties on the
+// primary key, so pages never shuffle events with equal keys.
+func applySort(q *eventQuery, sort string) {
+	if sort == sortRelevance {
+		q.orderBy = q.rank + " DESC, events.id DESC"
+
+		return
+	}
+
+	option := sortOptions[sort]
+	direction := sortDirection(option.descending)
+	q.orderBy = option.expression(q) + " " + direction + " NULLS LAST, events.id " + direction
+}
+
+// applyCursor continues after the cursor in the order set by applySort.
+func applyCursor(q *eventQuery, sort string, cursor *eventCursor) {
+	option := sortOptions[sort]
+	key := option.expression(q)
+
+	comparison := ">"
+	if option.descending {
+		comparison = "<"
+	}
+
+	id := q.arg(cursor.ID)
+	if cursor.Value == nil {
+		q.where("(" + key + " IS NULL AND events.id " + comparison + " " + id + ")")
+
+		return
+	}
+
+	value := q.arg(*cursor.Value) + "::" + option.cast
+	q.where("(" + key + " " + comparison + " " + value + " OR (" + key + " = " + value + " AND events.id " + comparison + " " + id + ") OR " + key + " IS NULL)")
+}
@@ -0,0 +1,180 @@
+package events
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+const maxCancellationReasonLength = 500
+
+var allowedStatusTransitions = map[string][]string{
+	models.EventStatusDraft:     {models.EventStatusPublished},
+	models.EventStatusPublished: {models.EventStatusCancelled, models.EventStatusCompleted},
+}
+
+func canTransition(from string, to string) bool {
+	for _, allowed := range allowedStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateInitialStatus(status string) (string, bool) {
+	switch status {
+	case "":
+		return models.EventStatusPublished, true
+	case models.EventStatusDraft, models.EventStatusPublished:
+		return status, true
+	}
+
+	return "", false
+}
+
+// changeStatus moves the event to the target status when the transition is
+// allowed and writes the error response otherwise.
+func (s *EventsService) changeStatus(c *gin.Context, target string, reason *string) bool {
+	eventId := c.Param("eventId")
+
+	if !s.validateUserCanManageEvent(c, eventId) {
+		return false
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Println("(changeStatus) db.Begin", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error changing event status"))
+
+		return false
+	}
+	defer tx.Rollback()
+
+	var current string
+	var startsAt time.Time
+	err = tx.QueryRow("SELECT status, starts_at FROM events WHERE public_id = $1 AND deleted_at IS NULL FOR UPDATE", eventId).Scan(&current, &startsAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+			return false
+		}
+
+		log.Println("(changeStatus) tx.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error changing event status"))
+
+		return false
+	}
+
+	if !canTransition(current, target) {
+		c.JSON(http.StatusConflict, utils.GetError("Event cannot be changed from "+current+" to "+target))
+
+		return false
+	}
+
+	if target == models.EventStatusCompleted && startsAt.After(time.Now()) {
+		c.JSON(http.StatusConflict, utils.GetError("Event cannot be completed before it takes place"))
+
+		return false
+	}
+
+	query := "UPDATE events SET status = $1, cancellation_reason = $2, status_changed_at = $3 WHERE public_id = $4 AND deleted_at IS NULL"
+	if _, err := tx.Exec(query, target, reason, time.Now(), eventId); err != nil {
+		log.Println("(changeStatus) tx.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error changing event status"))
+
+		return false
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("(changeStatus) tx.Commit", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error changing event status"))
+
+		return false
+	}
+
+	return true
+}
+
+// @Summary Publish an event
+// @Description Publishes a draft event, making it visible in public listings
+// @Tags events
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Success 200
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/publish [post]
+func (s *EventsService) PublishEvent(c *gin.Context) {
+	if !s.changeStatus(c, models.EventStatusPublished, nil) {
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Cancel an event
+// @Description Cancels a published event. The event and its requests stay visible with the cancelled status and reason.
+// @Tags events
+// @Accept json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param cancelInput body models.EventCancelInput true "Cancellation reason"
+// @Success 200
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/cancel [post]
+func (s *EventsService) CancelEvent(c *gin.Context) {
+	var input models.EventCancelInput
+	if err := c.BindJSON(&input); err != nil {
+		log.Println("(CancelEvent) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Error while parsing request body"))
+
+		return
+	}
+
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" || len([]rune(reason)) > maxCancellationReasonLength {
+		c.JSON(http.StatusBadRequest, utils.GetError("reason is required and must be at most 500 characters"))
+
+		return
+	}
+
+	if !s.changeStatus(c, models.EventStatusCancelled, &reason) {
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Complete an event
+// @Description Marks a published event that already took place as completed
+// @Tags events
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Success 200
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/complete [post]
+func (s *EventsService) CompleteEvent(c *gin.Context) {
+	if !s.changeStatus(c, models.EventStatusCompleted, nil) {
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
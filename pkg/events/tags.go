@@ -0,0 +1,71 @@
+package events
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+const (
+	maxTags      = 10
+	maxTagLength = 30
+	tagsColumn   = "ARRAY(SELECT tags.value FROM event_tags JOIN tags ON tags.id = event_tags.tag_id WHERE event_tags.event_id = events.public_id ORDER BY tags.value)"
+)
+
+// normalizeTag lowercases the tag and collapses its whitespace, so "Family
+// Friendly" and "family  friendly" end up as one tag.
+func normalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+}
+
+// normalizeTags normalizes the tags and drops empty and repeated ones.
+func normalizeTags(tags []string) []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	return result
+}
+
+func validateTags(errs *validationErrors, tags []string) {
+	if len(tags) > maxTags {
+		errs.add("tags", "at most "+strconv.Itoa(maxTags)+" tags are allowed")
+	}
+
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			errs.add("tags", "tag "+tag+" must be at most "+strconv.Itoa(maxTagLength)+" characters")
+		}
+	}
+}
+
+// saveTags replaces the tags of the event, creating the tags not used before.
+func saveTags(tx *sql.Tx, eventId string, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM event_tags WHERE event_id = $1", eventId); err != nil {
+		return err
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec("INSERT INTO tags (value) SELECT unnest($1::varchar[]) ON CONFLICT (value) DO NOTHING", pq.Array(tags))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO event_tags (event_id, tag_id) SELECT $1, id FROM tags WHERE value = ANY($2)", eventId, pq.Array(tags))
+
+	return err
+}
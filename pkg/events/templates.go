@@ -0,0 +1,361 @@
+package events
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+const templateColumns = "public_id, name, sport, location, latitude, longitude, price, description, level, capacity, created_at, updated_at"
+
+func getColumnsForTemplate(template *models.EventTemplate) []interface{} {
+	return []interface{}{&template.ID, &template.Name, &template.Sport, &template.Location, &template.Latitude, &template.Longitude,
+		&template.Price, &template.Description, &template.Level, &template.Capacity, &template.CreatedAt, &template.UpdatedAt}
+}
+
+// validateTemplate runs the field checks of events on the template preset and
+// writes the error response when it is not valid.
+func (s *EventsService) validateTemplate(c *gin.Context, input *models.EventTemplateInput) bool {
+	references, err := s.loadReferences()
+	if err != nil {
+		log.Println("(validateTemplate) loadReferences", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error validating template"))
+
+		return false
+	}
+
+	errs := validationErrors{}
+	eventFields{
+		name: &input.Name, sport: &input.Sport, location: &input.Location, level: &input.Level, description: &input.Description,
+		price: input.Price, capacity: input.Capacity, latitude: input.Latitude, longitude: input.Longitude,
+	}.validate(&errs, references)
+
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, utils.GetValidationError("Invalid template", errs))
+
+		return false
+	}
+
+	return true
+}
+
+// newEventInput fills in the fields every new event needs on top of the
+// copied preset. Without an explicit end the new event keeps the duration of
+// the preset.
+func newEventInput(preset models.EventInput, input models.NewEventInput) (models.EventInput, error) {
+	if input.StartsAt.IsZero() {
+		return preset, errors.New("startsAt is required")
+	}
+
+	switch {
+	case input.EndsAt != nil:
+		preset.EndsAt = input.EndsAt
+	case preset.EndsAt != nil:
+		endsAt := input.StartsAt.Add(preset.EndsAt.Sub(preset.StartsAt))
+		preset.EndsAt = &endsAt
+	}
+
+	preset.StartsAt = input.StartsAt
+	if input.Timezone != "" {
+		preset.Timezone = input.Timezone
+	}
+	preset.Status = input.Status
+	if input.Name != nil {
+		preset.Name = *input.Name
+	}
+
+	return preset, nil
+}
+
+func (s *EventsService) getTemplate(templateId string, userID string) (*models.EventTemplate, error) {
+	var template models.EventTemplate
+	query := "SELECT " + templateColumns + " FROM event_templates WHERE public_id = $1 AND owner_id = $2"
+	if err := s.db.QueryRow(query, templateId, userID).Scan(getColumnsForTemplate(&template)...); err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// @Summary Clone an event
+// @Description Creates a new event owned by the current user from an existing one, with a new start time. The exception dates, images, participants and comments are not copied.
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param clone body models.NewEventInput true "Start and optional end, timezone, name and status of the new event"
+// @Success 200 {object} models.Event
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid event, fields lists the problems"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/clone [post]
+func (s *EventsService) CloneEvent(c *gin.Context) {
+	eventId := c.Param("eventId")
+
+	var input models.NewEventInput
+	if err := c.BindJSON(&input); err != nil {
+		log.Println("(CloneEvent) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid request body"))
+
+		return
+	}
+
+	if !s.validateUserCanManageEvent(c, eventId) {
+		return
+	}
+
+	source, err := s.getEventInput(eventId)
+	if err != nil {
+		log.Println("(CloneEvent) getEventInput", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error cloning event"))
+
+		return
+	}
+
+	source.ExceptionDates = nil
+
+	inputEvent, err := newEventInput(*source, input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
+	}
+
+	s.createEvent(c, inputEvent)
+}
+
+// @Summary Get event templates
+// @Description Lists event templates of the current user
+// @Tags templates
+// @Produce json
+// @Success 200 {array} models.EventTemplate
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /templates [get]
+func (s *EventsService) GetTemplates(c *gin.Context) {
+	userID := c.GetString(constants.UserID_key)
+
+	rows, err := s.db.Query("SELECT "+templateColumns+" FROM event_templates WHERE owner_id = $1 ORDER BY name, id", userID)
+	if err != nil {
+		log.Println("(GetTemplates) db.Query", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving templates"))
+
+		return
+	}
+	defer rows.Close()
+
+	templates := []models.EventTemplate{}
+	for rows.Next() {
+		var template models.EventTemplate
+		if err := rows.Scan(getColumnsForTemplate(&template)...); err != nil {
+			log.Println("(GetTemplates) rows.Scan", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error processing templates"))
+
+			return
+		}
+
+		templates = append(templates, template)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println("(GetTemplates) rows.Err", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error reading templates"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// @Summary Create an event template
+// @Description Saves a reusable event preset for the current user
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param template body models.EventTemplateInput true "Template"
+// @Success 200 {object} models.EventTemplate
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid template, fields lists the problems"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /templates [post]
+func (s *EventsService) CreateTemplate(c *gin.Context) {
+	userID := c.GetString(constants.UserID_key)
+
+	var input models.EventTemplateInput
+	if err := c.BindJSON(&input); err != nil {
+		log.Println("(CreateTemplate) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid request body"))
+
+		return
+	}
+
+	if !s.validateTemplate(c, &input) {
+		return
+	}
+
+	var template models.EventTemplate
+	query := `
+		INSERT INTO event_templates (public_id, owner_id, name, sport, location, latitude, longitude, price, description, level, capacity)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING ` + templateColumns
+	err := s.db.QueryRow(query, utils.GenerateUUID(), userID, input.Name, input.Sport, input.Location, input.Latitude, input.Longitude,
+		input.Price, input.Description, input.Level, input.Capacity).Scan(getColumnsForTemplate(&template)...)
+	if err != nil {
+		log.Println("(CreateTemplate) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error creating template"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// @Summary Update an event template
+// @Description Replaces the preset of the template, events already created from it stay untouched
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param templateId path string true "Template ID" example(pwnrxtbi9z0v)
+// @Param template body models.EventTemplateInput true "Template"
+// @Success 200 {object} models.EventTemplate
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid template, fields lists the problems"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /templates/{templateId} [put]
+func (s *EventsService) UpdateTemplate(c *gin.Context) {
+	userID := c.GetString(constants.UserID_key)
+
+	var input models.EventTemplateInput
+	if err := c.BindJSON(&input); err != nil {
+		log.Println("(UpdateTemplate) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid request body"))
+
+		return
+	}
+
+	if !s.validateTemplate(c, &input) {
+		return
+	}
+
+	var template models.EventTemplate
+	query := `
+		UPDATE event_templates
+		SET name = $1, sport = $2, location = $3, latitude = $4, longitude = $5, price = $6, description = $7, level = $8, capacity = $9, updated_at = $10
+		WHERE public_id = $11 AND owner_id = $12
+		RETURNING ` + templateColumns
+	err := s.db.QueryRow(query, input.Name, input.Sport, input.Location, input.Latitude, input.Longitude, input.Price, input.Description,
+		input.Level, input.Capacity, time.Now(), c.Param("templateId"), userID).Scan(getColumnsForTemplate(&template)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, utils.GetError("Template not found"))
+
+			return
+		}
+
+		log.Println("(UpdateTemplate) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error updating template"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// @Summary Delete an event template
+// @Description Deletes the template, events already created from it stay untouched
+// @Tags templates
+// @Param templateId path string true "Template ID" example(pwnrxtbi9z0v)
+// @Success 200
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /templates/{templateId} [delete]
+func (s *EventsService) DeleteTemplate(c *gin.Context) {
+	userID := c.GetString(constants.UserID_key)
+
+	res, err := s.db.Exec("DELETE FROM event_templates WHERE public_id = $1 AND owner_id = $2", c.Param("templateId"), userID)
+	if err != nil {
+		log.Println("(DeleteTemplate) db.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error deleting template"))
+
+		return
+	}
+
+	if deleted, err := res.RowsAffected(); err != nil || deleted == 0 {
+		c.JSON(http.StatusNotFound, utils.GetError("Template not found"))
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Create an event from a template
+// @Description Creates a new event from the template preset. The event name defaults to the template name.
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param templateId path string true "Template ID" example(pwnrxtbi9z0v)
+// @Param event body models.NewEventInput true "Start and optional end, timezone, name and status of the new event"
+// @Success 200 {object} models.Event
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid event, fields lists the problems"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /templates/{templateId}/events [post]
+func (s *EventsService) CreateEventFromTemplate(c *gin.Context) {
+	userID := c.GetString(constants.UserID_key)
+
+	var input models.NewEventInput
+	if err := c.BindJSON(&input); err != nil {
+		log.Println("(CreateEventFromTemplate) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid request body"))
+
+		return
+	}
+
+	template, err := s.getTemplate(c.Param("templateId"), userID)
+	if err != nil {
+		log.Println("(CreateEventFromTemplate) getTemplate", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Template not found"))
+
+		return
+	}
+
+	preset := models.EventInput{
+		Name:        template.Name,
+		Sport:       template.Sport,
+		Location:    template.Location,
+		Latitude:    template.Latitude,
+		Longitude:   template.Longitude,
+		Price:       template.Price,
+		Description: template.Description,
+		Level:       template.Level,
+		Capacity:    template.Capacity,
+	}
+
+	inputEvent, err := newEventInput(preset, input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetError(err.Error()))
+
+		return
+	}
+
+	s.createEvent(c, inputEvent)
+}
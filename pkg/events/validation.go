@@ -0,0 +1,225 @@
+package events
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/pkg/venues"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+// length limits of the events columns
+const (
+	maxNameLength     = 100
+	maxSportLength    = 20
+	maxLocationLength = 50
+	maxLevelLength    = 30
+	maxPrice          = 32767
+	// descriptions are TEXT, the limit keeps them at a reasonable size
+	maxDescriptionLength = 5000
+)
+
+type validationErrors []models.FieldError
+
+func (errs *validationErrors) add(field string, message string) {
+	*errs = append(*errs, models.FieldError{Field: field, Message: message})
+}
+
+func (errs *validationErrors) text(field string, value *string, maxLength int) {
+	*value = strings.TrimSpace(*value)
+
+	if *value == "" {
+		errs.add(field, field+" is required")
+	} else if utf8.RuneCountInString(*value) > maxLength {
+		errs.add(field, field+" must be at most "+strconv.Itoa(maxLength)+" characters")
+	}
+}
+
+// description sanitizes the markup of the description and checks its length.
+func (errs *validationErrors) description(value *string) {
+	*value = utils.SanitizeHTML(*value)
+
+	if utf8.RuneCountInString(*value) > maxDescriptionLength {
+		errs.add("description", "description must be at most "+strconv.Itoa(maxDescriptionLength)+" characters")
+	}
+}
+
+// referenceValues returns the values of a reference table in their order.
+func (s *EventsService) referenceValues(table string) ([]string, error) {
+	rows, err := s.db.Query("SELECT value FROM " + table + " ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+// eventReferences are the allowed sports and levels, loaded once per request
+// so an import does not query them for every row.
+type eventReferences struct {
+	sports []string
+	levels []string
+}
+
+func (s *EventsService) loadReferences() (*eventReferences, error) {
+	sports, err := s.referenceValues("sports")
+	if err != nil {
+		return nil, err
+	}
+
+	levels, err := s.referenceValues("levels")
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventReferences{sports: sports, levels: levels}, nil
+}
+
+func validateReference(errs *validationErrors, field string, value string, values []string) {
+	if value == "" {
+		return
+	}
+
+	for _, allowed := range values {
+		if value == allowed {
+			return
+		}
+	}
+
+	errs.add(field, field+" must be one of "+strings.Join(values, ", "))
+}
+
+// applyVenue copies the name and coordinates of the referenced venue into the
+// event, the venue wins over the free-text location.
+func (s *EventsService) applyVenue(errs *validationErrors, input *models.EventInput) error {
+	if input.VenueID != nil && strings.TrimSpace(*input.VenueID) == "" {
+		input.VenueID = nil
+	}
+
+	if input.VenueID == nil {
+		return nil
+	}
+
+	venue, err := venues.GetVenue(s.db, *input.VenueID)
+	if err == sql.ErrNoRows {
+		errs.add("venueId", "venue not found")
+
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	input.Location = venue.Name
+	input.Latitude = venue.Latitude
+	input.Longitude = venue.Longitude
+
+	return nil
+}
+
+// eventFields point to the fields events and templates share, so both are
+// checked and normalized the same way.
+type eventFields struct {
+	name        *string
+	sport       *string
+	location    *string
+	level       *string
+	description *string
+	price       uint16
+	capacity    *uint16
+	latitude    *float64
+	longitude   *float64
+}
+
+func (fields eventFields) validate(errs *validationErrors, references *eventReferences) {
+	errs.text("name", fields.name, maxNameLength)
+	errs.text("sport", fields.sport, maxSportLength)
+	errs.text("location", fields.location, maxLocationLength)
+	errs.text("level", fields.level, maxLevelLength)
+
+	errs.description(fields.description)
+
+	validateReference(errs, "sport", *fields.sport, references.sports)
+	validateReference(errs, "level", *fields.level, references.levels)
+
+	if fields.price > maxPrice {
+		errs.add("price", "price must be at most "+strconv.Itoa(maxPrice))
+	}
+
+	if fields.capacity != nil && *fields.capacity == 0 {
+		errs.add("capacity", "capacity must be greater than 0")
+	}
+
+	if err := utils.ValidateCoordinates(fields.latitude, fields.longitude); err != nil {
+		errs.add("latitude", err.Error())
+	}
+}
+
+// validateEventInput checks the event against the schema limits and the
+// references. previous is the stored start of an existing event, nil for new
+// ones. The error is set only when the check itself failed.
+func (s *EventsService) validateEventInput(input *models.EventInput, previous *time.Time, references *eventReferences) ([]models.FieldError, error) {
+	errs := validationErrors{}
+
+	if err := s.applyVenue(&errs, input); err != nil {
+		return nil, err
+	}
+
+	eventFields{
+		name: &input.Name, sport: &input.Sport, location: &input.Location, level: &input.Level, description: &input.Description,
+		price: input.Price, capacity: input.Capacity, latitude: input.Latitude, longitude: input.Longitude,
+	}.validate(&errs, references)
+
+	input.Tags = normalizeTags(input.Tags)
+	validateTags(&errs, input.Tags)
+
+	validateSchedule(input, previous, &errs)
+
+	return errs, nil
+}
+
+// validateEvent runs validateEventInput and writes the error response when
+// the event is not valid.
+func (s *EventsService) validateEvent(c *gin.Context, input *models.EventInput, previous *time.Time) bool {
+	references, err := s.loadReferences()
+	if err != nil {
+		log.Println("(validateEvent) loadReferences", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error validating event"))
+
+		return false
+	}
+
+	fields, err := s.validateEventInput(input, previous, references)
+	if err != nil {
+		log.Println("(validateEvent) validateEventInput", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error validating event"))
+
+		return false
+	}
+
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, utils.GetValidationError("Invalid event", fields))
+
+		return false
+	}
+
+	return true
+}
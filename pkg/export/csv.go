@@ -0,0 +1,57 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	// the byte order mark makes Excel read the file as UTF-8
+	io.WriteString(w, "\ufeff")
+
+	return &csvWriter{csv.NewWriter(w)}
+}
+
+// escapeFormula keeps spreadsheets from evaluating user supplied text that
+// looks like a formula.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+func (w *csvWriter) Write(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		// numbers stay numbers, any other text can come from a user, joined
+		// tags included
+		switch value.(type) {
+		case int, float64:
+			record[i] = formatValue(value)
+		default:
+			record[i] = escapeFormula(formatValue(value))
+		}
+	}
+
+	if err := w.writer.Write(record); err != nil {
+		return err
+	}
+
+	// flush every row so the export streams
+	w.writer.Flush()
+
+	return w.writer.Error()
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+
+	return w.writer.Error()
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Writer writes a table row by row, so exports can be streamed straight to
+// the response. Values are strings, integers, floats, times or nil.
+type Writer interface {
+	Write(values []interface{}) error
+	Close() error
+}
+
+// NewWriter returns a writer for the format, the header is written first.
+func NewWriter(format string, w io.Writer, header []string) (Writer, error) {
+	var writer Writer
+	switch format {
+	case FormatCSV:
+		writer = newCSVWriter(w)
+	case FormatXLSX:
+		writer = newXLSXWriter(w)
+	default:
+		return nil, errors.New("Invalid format parameter, expected csv or xlsx")
+	}
+
+	values := make([]interface{}, len(header))
+	for i, name := range header {
+		values[i] = name
+	}
+
+	if err := writer.Write(values); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+// formatValue renders a value as text, times in RFC 3339 keeping their zone.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []string:
+		return strings.Join(v, "; ")
+	}
+
+	return ""
+}
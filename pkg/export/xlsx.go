@@ -0,0 +1,129 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// the smallest package Excel, LibreOffice and Google Sheets open: one
+// worksheet with inline strings and no styles
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`},
+}
+
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	row     int
+	err     error
+}
+
+func newXLSXWriter(w io.Writer) *xlsxWriter {
+	writer := &xlsxWriter{archive: zip.NewWriter(w)}
+
+	for _, part := range xlsxParts {
+		if writer.err != nil {
+			break
+		}
+
+		var file io.Writer
+		if file, writer.err = writer.archive.Create(part.name); writer.err == nil {
+			_, writer.err = io.WriteString(file, part.content)
+		}
+	}
+
+	// the worksheet is the last entry, so its rows can be written as they come
+	if writer.err == nil {
+		writer.sheet, writer.err = writer.archive.Create("xl/worksheets/sheet1.xml")
+	}
+
+	if writer.err == nil {
+		_, writer.err = io.WriteString(writer.sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n"+
+			`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	}
+
+	return writer
+}
+
+// columnName turns a zero based index into the A, B, ..., AA column name.
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+
+	return name
+}
+
+func escapeXML(value string) string {
+	var builder strings.Builder
+	xml.EscapeText(&builder, []byte(value))
+
+	return builder.String()
+}
+
+func (w *xlsxWriter) Write(values []interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	w.row++
+	row := strconv.Itoa(w.row)
+
+	var builder strings.Builder
+	builder.WriteString(`<row r="` + row + `">`)
+	for i, value := range values {
+		ref := columnName(i) + row
+
+		switch v := value.(type) {
+		case nil:
+			continue
+		case int, float64:
+			builder.WriteString(`<c r="` + ref + `"><v>` + formatValue(v) + `</v></c>`)
+		default:
+			builder.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">` + escapeXML(formatValue(v)) + `</t></is></c>`)
+		}
+	}
+	builder.WriteString(`</row>`)
+
+	_, w.err = io.WriteString(w.sheet, builder.String())
+
+	return w.err
+}
+
+func (w *xlsxWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if _, err := io.WriteString(w.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+
+	return w.archive.Close()
+}
@@ -0,0 +1,104 @@
+package messages
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/pkg/roles"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+// @Summary Mark attendance
+// @Description Marks the requester of an approved email request as attended or no-show once the event has started. The mark can be changed later and feeds the reliability of the requester. Available to the event owner and co-organizers.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Email Request ID" default(1)
+// @Param attendance body models.AttendanceInput true "Attendance"
+// @Success 200 {object} models.EmailRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /messages/email/{id}/attendance [put]
+func (s *MessageService) MarkAttendance(c *gin.Context) {
+	requestId := c.Param("requestId")
+	userID := c.GetString(constants.UserID_key)
+
+	var input models.AttendanceInput
+	if err := c.BindJSON(&input); err != nil {
+		log.Println("(MarkAttendance) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid request data"))
+
+		return
+	}
+
+	if input.Attendance != models.AttendanceAttended && input.Attendance != models.AttendanceNoShow {
+		c.JSON(http.StatusBadRequest, utils.GetError("attendance must be attended or no-show"))
+
+		return
+	}
+
+	var approved *bool
+	var startsAt time.Time
+	query := `
+		SELECT email_requests.approved, events.starts_at
+		FROM email_requests
+		JOIN events ON events.public_id = email_requests.event_id AND events.deleted_at IS NULL
+		WHERE email_requests.id = $1 AND email_requests.event_id IN (
+			SELECT event_roles.event_id FROM event_roles WHERE event_roles.user_id = $2 AND event_roles.role IN ` + roles.OrganizerRoles + `
+		)
+	`
+	err := s.db.QueryRow(query, requestId, userID).Scan(&approved, &startsAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, utils.GetError("Email request not found"))
+
+			return
+		}
+
+		log.Println("(MarkAttendance) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error marking attendance"))
+
+		return
+	}
+
+	if approved == nil || !*approved {
+		c.JSON(http.StatusBadRequest, utils.GetError("Attendance can be marked only for approved requests"))
+
+		return
+	}
+
+	now := time.Now()
+	if startsAt.After(now) {
+		c.JSON(http.StatusBadRequest, utils.GetError("Attendance can be marked once the event has started"))
+
+		return
+	}
+
+	query = `
+		UPDATE email_requests
+		SET attendance = $1, attendance_marked_at = $2, updated_at = $2
+		WHERE id = $3
+		RETURNING id, text, event_id, event_owner_id, requester_id, approved, approved_at, checked_in_at, attendance, created_at, updated_at
+	`
+	var emailRequest models.EmailRequest
+	err = s.db.QueryRow(query, input.Attendance, now, requestId).Scan(
+		&emailRequest.ID, &emailRequest.Text, &emailRequest.EventID, &emailRequest.EventOwnerID, &emailRequest.RequesterID,
+		&emailRequest.Approved, &emailRequest.ApprovedAt, &emailRequest.CheckedInAt, &emailRequest.Attendance,
+		&emailRequest.CreatedAt, &emailRequest.UpdatedAt,
+	)
+	if err != nil {
+		log.Println("(MarkAttendance) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error marking attendance"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, emailRequest)
+}
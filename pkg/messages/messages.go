@@ -10,6 +10,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/globus303/sportujspolu/constants"
 	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/pkg/roles"
+	"github.com/globus303/sportujspolu/pkg/user"
 	"github.com/globus303/sportujspolu/utils"
 )
 
@@ -43,8 +45,8 @@ func (s *MessageService) SendEmailRequest(c *gin.Context) {
 	requesterID := c.GetString(constants.UserID_key)
 
 	var eventOwnerID string
-	query := "SELECT owner_id FROM events WHERE public_id = $1 AND owner_id != $2"
-	err := s.db.QueryRow(query, inputEmailRequest.EventID, requesterID).Scan(&eventOwnerID)
+	query := "SELECT owner_id FROM events WHERE public_id = $1 AND owner_id != $2 AND status = $3 AND deleted_at IS NULL"
+	err := s.db.QueryRow(query, inputEmailRequest.EventID, requesterID, models.EventStatusPublished).Scan(&eventOwnerID)
 	if err != nil {
 		log.Println("(SendEmailRequest) db.QueryRow", err)
 		c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
@@ -100,7 +102,7 @@ func (s *MessageService) SendEmailRequest(c *gin.Context) {
 }
 
 // @Summary Approve an email request
-// @Description Approves an email request for a given ID
+// @Description Approves an email request for a given ID and issues the requester a check-in ticket. Available to the event owner and co-organizers.
 // @Tags messages
 // @Accept json
 // @Produce json
@@ -123,8 +125,10 @@ func (s *MessageService) ApproveEmailRequest(c *gin.Context) {
 
 	query := `
 		UPDATE email_requests
-		SET approved = $1, approved_at = $2, updated_at = $3
-		WHERE id = $4 AND event_owner_id = $5 AND approved IS NULL AND requester_id != $5
+		SET approved = $1, approved_at = $2, updated_at = $3, ticket_id = CASE WHEN $1 THEN $6 END
+		WHERE id = $4 AND approved IS NULL AND requester_id != $5 AND event_id IN (
+			SELECT event_roles.event_id FROM event_roles WHERE event_roles.user_id = $5 AND event_roles.role IN ` + roles.OrganizerRoles + `
+		)
 		RETURNING
       id,
       text,
@@ -133,6 +137,8 @@ func (s *MessageService) ApproveEmailRequest(c *gin.Context) {
       requester_id,
       approved,
       approved_at,
+      checked_in_at,
+      attendance,
       created_at,
       updated_at,
       (SELECT users.email FROM users WHERE users.id = email_requests.requester_id) AS requester_email
@@ -141,9 +147,11 @@ func (s *MessageService) ApproveEmailRequest(c *gin.Context) {
 	userID := c.GetString(constants.UserID_key)
 
 	var emailRequest models.EmailRequestApproveResponse
-	err := s.db.QueryRow(query, approveInput.Approved, time.Now(), time.Now(), requestId, userID).Scan(
+	// approved requesters get a ticket, checked in at the event by its QR code
+	err := s.db.QueryRow(query, approveInput.Approved, time.Now(), time.Now(), requestId, userID, utils.GenerateUUID()).Scan(
 		&emailRequest.ID, &emailRequest.Text, &emailRequest.EventID, &emailRequest.EventOwnerID,
-		&emailRequest.RequesterID, &emailRequest.Approved, &emailRequest.ApprovedAt, &emailRequest.CreatedAt, &emailRequest.UpdatedAt, &emailRequest.RequesterEmail,
+		&emailRequest.RequesterID, &emailRequest.Approved, &emailRequest.ApprovedAt, &emailRequest.CheckedInAt, &emailRequest.Attendance,
+		&emailRequest.CreatedAt, &emailRequest.UpdatedAt, &emailRequest.RequesterEmail,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -199,14 +207,23 @@ func getEmailRequests(c *gin.Context, s *MessageService, query string) error {
 			&emailRequest.RequesterID,
 			&emailRequest.Approved,
 			&emailRequest.ApprovedAt,
+			&emailRequest.CheckedInAt,
+			&emailRequest.Attendance,
 			&emailRequest.CreatedAt,
 			&emailRequest.UpdatedAt,
 			&emailRequest.RequesterName,
 			&emailRequest.RequesterEmail,
+			&emailRequest.RequesterAttended,
+			&emailRequest.RequesterNoShows,
 			&emailRequest.EventName,
 			&emailRequest.EventLocation,
 			&emailRequest.EventLevel,
 			&emailRequest.EventSport,
+			&emailRequest.EventStatus,
+			&emailRequest.EventCancellationReason,
+			&emailRequest.EventTimezone,
+			&emailRequest.EventStartsAt,
+			&emailRequest.EventEndsAt,
 			&emailRequest.EventOwnerName,
 			&emailRequest.EventOwnerEmail,
 		)
@@ -217,6 +234,18 @@ func getEmailRequests(c *gin.Context, s *MessageService, query string) error {
 			return err
 		}
 
+		if emailRequest.EventTimezone != nil {
+			for _, value := range []*time.Time{emailRequest.EventStartsAt, emailRequest.EventEndsAt} {
+				if value != nil {
+					*value = utils.InTimezone(*value, *emailRequest.EventTimezone)
+				}
+			}
+		}
+
+		if emailRequest.RequesterAttended != nil && emailRequest.RequesterNoShows != nil {
+			emailRequest.RequesterReliability = user.Reliability(*emailRequest.RequesterAttended, *emailRequest.RequesterNoShows)
+		}
+
 		emailRequests = append(emailRequests, emailRequest)
 	}
 
@@ -247,14 +276,23 @@ func (s *MessageService) GetAllSentEmailRequests(c *gin.Context) {
           email_requests.requester_id,
           email_requests.approved,
           email_requests.approved_at,
+          email_requests.checked_in_at,
+          email_requests.attendance,
           email_requests.created_at,
           email_requests.updated_at,
           NULL AS requester_name,
           NULL AS requester_email,
+          NULL AS requester_attended,
+          NULL AS requester_no_shows,
           events.name AS event_name,
           events.location AS event_location,
           events.level AS event_level,
           events.sport AS event_sport,
+          events.status AS event_status,
+          events.cancellation_reason AS event_cancellation_reason,
+          events.timezone AS event_timezone,
+          events.starts_at AS event_starts_at,
+          events.ends_at AS event_ends_at,
           event_owner.name AS event_owner_name,
              (CASE
             WHEN email_requests.approved = true
@@ -265,7 +303,7 @@ func (s *MessageService) GetAllSentEmailRequests(c *gin.Context) {
         FROM email_requests
         LEFT JOIN users as event_owner ON event_owner.id = email_requests.event_owner_id
         LEFT JOIN events ON events.public_id = email_requests.event_id
-        WHERE email_requests.requester_id = $1 AND events.public_id IS NOT NULL
+        WHERE email_requests.requester_id = $1 AND events.public_id IS NOT NULL AND events.deleted_at IS NULL
 `
 
 	err := getEmailRequests(c, s, query)
@@ -275,7 +313,7 @@ func (s *MessageService) GetAllSentEmailRequests(c *gin.Context) {
 }
 
 // @Summary Get all email requests received as owner
-// @Description Retrieve all email requests for owner from the database
+// @Description Retrieve all email requests for events the user owns or co-organizes from the database, with the attendance record of each requester
 // @Tags messages
 // @Produce json
 // @Param approvedFilter query string false "Approved filter" Enums(true, false, null) default(null)
@@ -292,6 +330,8 @@ func (s *MessageService) GetAllReceivedOwnerEmailRequests(c *gin.Context) {
           email_requests.requester_id,
           email_requests.approved,
           email_requests.approved_at,
+          email_requests.checked_in_at,
+          email_requests.attendance,
           email_requests.created_at,
           email_requests.updated_at,
           requester.name AS requester_name,
@@ -300,17 +340,25 @@ func (s *MessageService) GetAllReceivedOwnerEmailRequests(c *gin.Context) {
             THEN requester.email
             ELSE NULL
           END) AS requester_email,
+          ` + user.AttendanceColumns("requester.id") + `,
           events.name AS event_name,
           events.location AS event_location,
           events.level AS event_level,
           events.sport AS event_sport,
+          events.status AS event_status,
+          events.cancellation_reason AS event_cancellation_reason,
+          events.timezone AS event_timezone,
+          events.starts_at AS event_starts_at,
+          events.ends_at AS event_ends_at,
           NULL AS event_owner_name,
           NULL AS event_owner_email
 
         FROM email_requests
         LEFT JOIN users AS requester ON requester.id =  email_requests.requester_id
         LEFT JOIN events ON events.public_id = email_requests.event_id
-        WHERE email_requests.event_owner_id = $1 AND events.public_id IS NOT NULL
+        WHERE events.deleted_at IS NULL AND requester.deleted_at IS NULL AND events.public_id IN (
+          SELECT event_roles.event_id FROM event_roles WHERE event_roles.user_id = $1 AND event_roles.role IN ` + roles.OrganizerRoles + `
+        )
 `
 
 	err := getEmailRequests(c, s, query)
@@ -0,0 +1,230 @@
+package participants
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/pkg/roles"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+type ParticipantsService struct {
+	db *sql.DB
+}
+
+func NewParticipantsService(db *sql.DB) *ParticipantsService {
+	return &ParticipantsService{db}
+}
+
+// @Summary Join an event
+// @Description Joins the current user to the event. Fails with 409 when the event is full or the user already joined.
+// @Tags participants
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Success 200 {object} models.ParticipationResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/participants [post]
+func (s *ParticipantsService) JoinEvent(c *gin.Context) {
+	eventId := c.Param("eventId")
+	userID := c.GetString(constants.UserID_key)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Println("(JoinEvent) db.Begin", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error joining event"))
+
+		return
+	}
+	defer tx.Rollback()
+
+	// Locking the event row serializes concurrent joins, so the count below
+	// cannot go stale before our insert commits.
+	var ownerID string
+	var capacity *int
+	var status string
+	err = tx.QueryRow("SELECT owner_id, capacity, status FROM events WHERE public_id = $1 AND deleted_at IS NULL FOR UPDATE", eventId).Scan(&ownerID, &capacity, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+			return
+		}
+
+		log.Println("(JoinEvent) tx.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error joining event"))
+
+		return
+	}
+
+	if ownerID == userID {
+		c.JSON(http.StatusBadRequest, utils.GetError("You cannot join your own event"))
+
+		return
+	}
+
+	if status != models.EventStatusPublished {
+		c.JSON(http.StatusConflict, utils.GetError("Event is not open for joining"))
+
+		return
+	}
+
+	var participantsCount int
+	err = tx.QueryRow("SELECT COUNT(*) FROM event_participants WHERE event_id = $1", eventId).Scan(&participantsCount)
+	if err != nil {
+		log.Println("(JoinEvent) tx.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error joining event"))
+
+		return
+	}
+
+	if capacity != nil && participantsCount >= *capacity {
+		c.JSON(http.StatusConflict, utils.GetError("Event is full"))
+
+		return
+	}
+
+	participation := models.ParticipationResponse{
+		EventID:  eventId,
+		UserID:   userID,
+		JoinedAt: time.Now(),
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO event_participants (event_id, user_id, joined_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (event_id, user_id) DO NOTHING
+	`, participation.EventID, participation.UserID, participation.JoinedAt)
+	if err != nil {
+		log.Println("(JoinEvent) tx.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error joining event"))
+
+		return
+	}
+
+	if inserted, err := res.RowsAffected(); err != nil || inserted == 0 {
+		c.JSON(http.StatusConflict, utils.GetError("You already joined this event"))
+
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("(JoinEvent) tx.Commit", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error joining event"))
+
+		return
+	}
+
+	if capacity != nil {
+		spotsLeft := *capacity - participantsCount - 1
+		participation.SpotsLeft = &spotsLeft
+	}
+
+	c.JSON(http.StatusOK, participation)
+}
+
+// @Summary Leave an event
+// @Description Removes the current user from the event participants
+// @Tags participants
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Success 200
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/participants [delete]
+func (s *ParticipantsService) LeaveEvent(c *gin.Context) {
+	eventId := c.Param("eventId")
+	userID := c.GetString(constants.UserID_key)
+
+	res, err := s.db.Exec("DELETE FROM event_participants WHERE event_id = $1 AND user_id = $2", eventId, userID)
+	if err != nil {
+		log.Println("(LeaveEvent) db.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error leaving event"))
+
+		return
+	}
+
+	if deleted, err := res.RowsAffected(); err != nil || deleted == 0 {
+		c.JSON(http.StatusNotFound, utils.GetError("You are not a participant of this event"))
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Get event participants
+// @Description Lists participants of the event, available to the event owner and co-organizers
+// @Tags participants
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Success 200 {array} models.Participant
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/participants [get]
+func (s *ParticipantsService) GetEventParticipants(c *gin.Context) {
+	eventId := c.Param("eventId")
+	userID := c.GetString(constants.UserID_key)
+
+	role, err := roles.GetUserRole(s.db, eventId, userID)
+	if err != nil {
+		log.Println("(GetEventParticipants) roles.GetUserRole", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+		return
+	}
+
+	if !roles.CanManage(role) {
+		c.JSON(http.StatusForbidden, utils.GetError("You are not an organizer of this event"))
+
+		return
+	}
+
+	query := `
+		SELECT users.id, users.name, users.email, event_participants.joined_at
+		FROM event_participants
+		JOIN users ON users.id = event_participants.user_id AND users.deleted_at IS NULL
+		WHERE event_participants.event_id = $1
+		ORDER BY event_participants.joined_at
+	`
+	rows, err := s.db.Query(query, eventId)
+	if err != nil {
+		log.Println("(GetEventParticipants) db.Query", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving participants"))
+
+		return
+	}
+	defer rows.Close()
+
+	participants := []models.Participant{}
+	for rows.Next() {
+		var participant models.Participant
+		if err := rows.Scan(&participant.UserID, &participant.Name, &participant.Email, &participant.JoinedAt); err != nil {
+			log.Println("(GetEventParticipants) rows.Scan", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error processing participants"))
+
+			return
+		}
+
+		participants = append(participants, participant)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println("(GetEventParticipants) rows.Err", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error reading participants"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, participants)
+}
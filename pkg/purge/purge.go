@@ -0,0 +1,108 @@
+package purge
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/pkg/storage"
+	"github.com/lib/pq"
+)
+
+// Purger permanently removes events and users that were soft deleted longer
+// than the retention window ago, together with their stored images.
+type Purger struct {
+	db      *sql.DB
+	storage storage.Storage
+}
+
+func NewPurger(db *sql.DB, storage storage.Storage) *Purger {
+	return &Purger{db, storage}
+}
+
+// Start purges right away and then every interval in the background.
+func (p *Purger) Start(interval time.Duration) {
+	go func() {
+		for {
+			if err := p.Purge(); err != nil {
+				log.Println("(Purger) Purge", err)
+			}
+
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func (p *Purger) Purge() error {
+	cutoff := time.Now().Add(-constants.RetentionWindow)
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// events of purged users were soft deleted with them, so they are
+	// covered by the same cutoff
+	var eventIds []string
+	err = tx.QueryRow("SELECT COALESCE(array_agg(public_id), '{}') FROM events WHERE deleted_at < $1", cutoff).Scan(pq.Array(&eventIds))
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("DELETE FROM event_images WHERE event_id = ANY($1) RETURNING image_key, thumbnail_key", pq.Array(eventIds))
+	if err != nil {
+		return err
+	}
+
+	keys := []string{}
+	for rows.Next() {
+		var imageKey, thumbnailKey string
+		if err := rows.Scan(&imageKey, &thumbnailKey); err != nil {
+			rows.Close()
+
+			return err
+		}
+
+		keys = append(keys, imageKey, thumbnailKey)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// email requests have no foreign keys, so they are removed explicitly,
+	// venues and the other user data cascade with the users
+	statements := []struct {
+		query string
+		arg   interface{}
+	}{
+		{"DELETE FROM email_requests WHERE event_id = ANY($1)", pq.Array(eventIds)},
+		{"DELETE FROM events WHERE public_id = ANY($1)", pq.Array(eventIds)},
+		{"DELETE FROM email_requests WHERE requester_id IN (SELECT id FROM users WHERE deleted_at < $1)", cutoff},
+		{"DELETE FROM users WHERE deleted_at < $1", cutoff},
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement.query, statement.arg); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := p.storage.Delete(key); err != nil {
+			log.Println("(Purge) storage.Delete", key, err)
+		}
+	}
+
+	if len(eventIds) > 0 {
+		log.Println("(Purge) purged events", len(eventIds))
+	}
+
+	return nil
+}
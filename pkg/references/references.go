@@ -4,12 +4,15 @@ import (
 	"database/sql"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/globus303/sportujspolu/models"
 	"github.com/globus303/sportujspolu/utils"
 )
 
+const maxTagsLimit = 100
+
 type ReferencesService struct {
 	db *sql.DB
 }
@@ -59,3 +62,105 @@ func (s *ReferencesService) GetAllLevels(c *gin.Context) {
 
 	c.JSON(http.StatusOK, levels)
 }
+
+// @Summary Get all sports
+// @Description Retrieves all sports from the database, events have to use one of the values
+// @Tags sports
+// @Produce json
+// @Success 200 {array} models.Sport
+// @Failure 500 {object} models.ErrorResponse
+// @Router /references/sports [get]
+func (s *ReferencesService) GetAllSports(c *gin.Context) {
+	sports := []models.Sport{}
+
+	query := "SELECT id, value, label FROM sports ORDER BY label"
+	rows, err := s.db.Query(query)
+	if err != nil {
+		log.Println("(GetAllSports) db.Query", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving sports"))
+
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sport models.Sport
+		if err := rows.Scan(&sport.ID, &sport.Value, &sport.Label); err != nil {
+			log.Println("(GetAllSports) rows.Scan", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error processing sports"))
+
+			return
+		}
+
+		sports = append(sports, sport)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println("(GetAllSports) rows.Err", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error reading sports"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, sports)
+}
+
+// @Summary Get popular tags
+// @Description Retrieves the tags used by listed events, the most used first
+// @Tags tags
+// @Produce json
+// @Param limit query int false "Number of tags, at most 100" default(30)
+// @Success 200 {array} models.Tag
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /references/tags [get]
+func (s *ReferencesService) GetPopularTags(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "30"))
+	if err != nil || limit < 1 || limit > maxTagsLimit {
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid limit parameter"))
+
+		return
+	}
+
+	tags := []models.Tag{}
+
+	query := `
+		SELECT tags.value, COUNT(*) AS count
+		FROM tags
+		JOIN event_tags ON event_tags.tag_id = tags.id
+		JOIN events ON events.public_id = event_tags.event_id
+		WHERE events.deleted_at IS NULL AND events.status = $1
+		GROUP BY tags.value
+		ORDER BY count DESC, tags.value
+		LIMIT $2
+	`
+	rows, err := s.db.Query(query, models.EventStatusPublished, limit)
+	if err != nil {
+		log.Println("(GetPopularTags) db.Query", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving tags"))
+
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Value, &tag.Count); err != nil {
+			log.Println("(GetPopularTags) rows.Scan", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error processing tags"))
+
+			return
+		}
+
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println("(GetPopularTags) rows.Err", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error reading tags"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
@@ -0,0 +1,245 @@
+package roles
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+// OrganizerRoles is the SQL list of roles allowed to manage an event.
+const OrganizerRoles = "('" + models.EventRoleOwner + "', '" + models.EventRoleCoOrganizer + "')"
+
+// GetUserRole returns the role of the user in the event, or an empty string
+// when the user has none. sql.ErrNoRows means the event does not exist.
+func GetUserRole(db *sql.DB, eventId string, userID string) (string, error) {
+	var role string
+	query := "SELECT COALESCE((SELECT role FROM event_roles WHERE event_roles.event_id = events.public_id AND event_roles.user_id = $2), '') FROM events WHERE public_id = $1 AND deleted_at IS NULL"
+	err := db.QueryRow(query, eventId, userID).Scan(&role)
+
+	return role, err
+}
+
+// CanManage tells whether the role may edit the event and handle its requests.
+func CanManage(role string) bool {
+	return role == models.EventRoleOwner || role == models.EventRoleCoOrganizer
+}
+
+// CanModerate tells whether the role may moderate the event comments.
+func CanModerate(role string) bool {
+	return CanManage(role) || role == models.EventRoleModerator
+}
+
+type RolesService struct {
+	db *sql.DB
+}
+
+func NewRolesService(db *sql.DB) *RolesService {
+	return &RolesService{db}
+}
+
+// @Summary Get event roles
+// @Description Lists the owner, co-organizers and moderators of the event, available to any of them
+// @Tags roles
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Success 200 {array} models.EventRole
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/roles [get]
+func (s *RolesService) GetEventRoles(c *gin.Context) {
+	eventId := c.Param("eventId")
+	userID := c.GetString(constants.UserID_key)
+
+	role, err := GetUserRole(s.db, eventId, userID)
+	if err != nil {
+		log.Println("(GetEventRoles) GetUserRole", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+		return
+	}
+
+	if role == "" {
+		c.JSON(http.StatusForbidden, utils.GetError("You are not an organizer of this event"))
+
+		return
+	}
+
+	query := `
+		SELECT users.id, users.name, users.email, event_roles.role, event_roles.created_at
+		FROM event_roles
+		JOIN users ON users.id = event_roles.user_id AND users.deleted_at IS NULL
+		WHERE event_roles.event_id = $1
+		ORDER BY event_roles.role = 'owner' DESC, event_roles.created_at
+	`
+	rows, err := s.db.Query(query, eventId)
+	if err != nil {
+		log.Println("(GetEventRoles) db.Query", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving roles"))
+
+		return
+	}
+	defer rows.Close()
+
+	roles := []models.EventRole{}
+	for rows.Next() {
+		var eventRole models.EventRole
+		if err := rows.Scan(&eventRole.UserID, &eventRole.Name, &eventRole.Email, &eventRole.Role, &eventRole.CreatedAt); err != nil {
+			log.Println("(GetEventRoles) rows.Scan", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error processing roles"))
+
+			return
+		}
+
+		roles = append(roles, eventRole)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println("(GetEventRoles) rows.Err", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error reading roles"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// @Summary Add an event organizer
+// @Description Gives a registered user the co-organizer or moderator role, or changes the role they already have. Available only to the event owner.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param role body models.EventRoleInput true "User email and role"
+// @Success 200 {object} models.EventRole
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/roles [post]
+func (s *RolesService) AddEventRole(c *gin.Context) {
+	eventId := c.Param("eventId")
+	userID := c.GetString(constants.UserID_key)
+
+	var input models.EventRoleInput
+	if err := c.BindJSON(&input); err != nil {
+		log.Println("(AddEventRole) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid request body"))
+
+		return
+	}
+
+	if input.Role != models.EventRoleCoOrganizer && input.Role != models.EventRoleModerator {
+		c.JSON(http.StatusBadRequest, utils.GetError("role must be co-organizer or moderator"))
+
+		return
+	}
+
+	role, err := GetUserRole(s.db, eventId, userID)
+	if err != nil {
+		log.Println("(AddEventRole) GetUserRole", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+		return
+	}
+
+	if role != models.EventRoleOwner {
+		c.JSON(http.StatusForbidden, utils.GetError("You are not the owner of this event"))
+
+		return
+	}
+
+	eventRole := models.EventRole{Email: input.Email, Role: input.Role}
+	err = s.db.QueryRow("SELECT id, name FROM users WHERE email = $1 AND deleted_at IS NULL", input.Email).Scan(&eventRole.UserID, &eventRole.Name)
+	if err != nil {
+		log.Println("(AddEventRole) db.QueryRow", err)
+		c.JSON(http.StatusNotFound, utils.GetError("User not found"))
+
+		return
+	}
+
+	if eventRole.UserID == userID {
+		c.JSON(http.StatusBadRequest, utils.GetError("You already own this event"))
+
+		return
+	}
+
+	query := `
+		INSERT INTO event_roles (event_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (event_id, user_id) DO UPDATE SET role = EXCLUDED.role
+		RETURNING created_at
+	`
+	if err := s.db.QueryRow(query, eventId, eventRole.UserID, eventRole.Role).Scan(&eventRole.CreatedAt); err != nil {
+		log.Println("(AddEventRole) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error adding organizer"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, eventRole)
+}
+
+// @Summary Remove an event organizer
+// @Description Removes the role of the user in the event. The owner can remove anyone else, other organizers can remove only themselves.
+// @Tags roles
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param userId path string true "User ID" example(pwnrxtbi9z0v)
+// @Success 200
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/roles/{userId} [delete]
+func (s *RolesService) RemoveEventRole(c *gin.Context) {
+	eventId := c.Param("eventId")
+	memberID := c.Param("userId")
+	userID := c.GetString(constants.UserID_key)
+
+	role, err := GetUserRole(s.db, eventId, userID)
+	if err != nil {
+		log.Println("(RemoveEventRole) GetUserRole", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+		return
+	}
+
+	if role != models.EventRoleOwner && memberID != userID {
+		c.JSON(http.StatusForbidden, utils.GetError("You are not the owner of this event"))
+
+		return
+	}
+
+	if role == models.EventRoleOwner && memberID == userID {
+		c.JSON(http.StatusBadRequest, utils.GetError("The owner cannot be removed"))
+
+		return
+	}
+
+	res, err := s.db.Exec("DELETE FROM event_roles WHERE event_id = $1 AND user_id = $2 AND role != $3", eventId, memberID, models.EventRoleOwner)
+	if err != nil {
+		log.Println("(RemoveEventRole) db.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error removing organizer"))
+
+		return
+	}
+
+	if deleted, err := res.RowsAffected(); err != nil || deleted == 0 {
+		c.JSON(http.StatusNotFound, utils.GetError("Organizer not found"))
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
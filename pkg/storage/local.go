@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type LocalStorage struct {
+	root    string
+	baseURL string
+}
+
+// NewLocalStorage stores files under root, which the router serves at baseURL.
+func NewLocalStorage(root string, baseURL string) *LocalStorage {
+	return &LocalStorage{root: root, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (s *LocalStorage) filePath(key string) (string, error) {
+	cleanKey := path.Clean("/" + key)
+	if cleanKey == "/" || strings.Contains(key, "..") {
+		return "", errors.New("invalid storage key " + key)
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(cleanKey)), nil
+}
+
+func (s *LocalStorage) Save(key string, data []byte) (string, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(filePath, data, 0o644); err != nil {
+		return "", err
+	}
+
+	return s.baseURL + path.Clean("/"+key), nil
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
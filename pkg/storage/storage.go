@@ -0,0 +1,8 @@
+package storage
+
+// Storage keeps uploaded files. Keys are slash separated paths generated by
+// the app, Save returns the public URL of the stored file.
+type Storage interface {
+	Save(key string, data []byte) (string, error)
+	Delete(key string) error
+}
@@ -0,0 +1,217 @@
+package tickets
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/pkg/roles"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+type TicketsService struct {
+	db *sql.DB
+}
+
+func NewTicketsService(db *sql.DB) *TicketsService {
+	return &TicketsService{db}
+}
+
+// getTicket returns the ticket of the user's approved request for the event.
+func (s *TicketsService) getTicket(eventId string, userID string) (models.Ticket, error) {
+	ticket := models.Ticket{EventID: eventId}
+	query := `
+		SELECT email_requests.ticket_id, email_requests.checked_in_at
+		FROM email_requests
+		JOIN events ON events.public_id = email_requests.event_id AND events.deleted_at IS NULL
+		WHERE email_requests.event_id = $1 AND email_requests.requester_id = $2
+			AND email_requests.approved = true AND email_requests.ticket_id IS NOT NULL
+	`
+	if err := s.db.QueryRow(query, eventId, userID).Scan(&ticket.TicketID, &ticket.CheckedInAt); err != nil {
+		return ticket, err
+	}
+
+	token, err := utils.SignTicket(eventId, ticket.TicketID)
+	ticket.Token = token
+
+	return ticket, err
+}
+
+// @Summary Get my ticket
+// @Description Returns the check-in ticket of the current user, issued when their request to join the event was approved
+// @Tags tickets
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Success 200 {object} models.Ticket
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/ticket [get]
+func (s *TicketsService) GetTicket(c *gin.Context) {
+	eventId := c.Param("eventId")
+	userID := c.GetString(constants.UserID_key)
+
+	ticket, err := s.getTicket(eventId, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, utils.GetError("Ticket not found"))
+
+			return
+		}
+
+		log.Println("(GetTicket) getTicket", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving ticket"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, ticket)
+}
+
+// @Summary Get my ticket QR code
+// @Description Returns the ticket token of the current user as a PNG QR code, scanned by the organizers at check-in
+// @Tags tickets
+// @Produce png
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Success 200 {file} binary
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/ticket/qr [get]
+func (s *TicketsService) GetTicketQR(c *gin.Context) {
+	eventId := c.Param("eventId")
+	userID := c.GetString(constants.UserID_key)
+
+	ticket, err := s.getTicket(eventId, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, utils.GetError("Ticket not found"))
+
+			return
+		}
+
+		log.Println("(GetTicketQR) getTicket", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving ticket"))
+
+		return
+	}
+
+	image, err := utils.QRCodePNG([]byte(ticket.Token))
+	if err != nil {
+		log.Println("(GetTicketQR) utils.QRCodePNG", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error rendering ticket"))
+
+		return
+	}
+
+	// the code grants entry, so shared caches must not keep it
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "image/png", image)
+}
+
+// @Summary Check in a participant
+// @Description Checks in the holder of the scanned ticket and marks them as attended. Each ticket can be checked in only once, a repeated scan fails with 409. Available to the event owner and co-organizers.
+// @Tags tickets
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID" example(q76j5d1a3xtn)
+// @Param checkIn body models.CheckInInput true "Scanned ticket token"
+// @Success 200 {object} models.CheckIn
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /events/{eventId}/check-in [post]
+func (s *TicketsService) CheckIn(c *gin.Context) {
+	eventId := c.Param("eventId")
+	userID := c.GetString(constants.UserID_key)
+
+	var input models.CheckInInput
+	if err := c.BindJSON(&input); err != nil {
+		log.Println("(CheckIn) c.BindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid request body"))
+
+		return
+	}
+
+	role, err := roles.GetUserRole(s.db, eventId, userID)
+	if err != nil {
+		log.Println("(CheckIn) GetUserRole", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Event not found"))
+
+		return
+	}
+
+	if !roles.CanManage(role) {
+		c.JSON(http.StatusForbidden, utils.GetError("You are not an organizer of this event"))
+
+		return
+	}
+
+	ticketId, err := utils.VerifyTicket(input.Token, eventId)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidTicket) || errors.Is(err, utils.ErrTicketEvent) {
+			c.JSON(http.StatusBadRequest, utils.GetError("Invalid ticket: "+err.Error()))
+
+			return
+		}
+
+		log.Println("(CheckIn) utils.VerifyTicket", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error verifying ticket"))
+
+		return
+	}
+
+	// the condition on checked_in_at makes concurrent scans of one ticket
+	// check it in only once
+	query := `
+		UPDATE email_requests
+		SET checked_in_at = $1, checked_in_by = $2, attendance = 'attended', attendance_marked_at = $1
+		WHERE event_id = $3 AND ticket_id = $4 AND approved = true AND checked_in_at IS NULL
+		RETURNING requester_id, checked_in_at
+	`
+	checkIn := models.CheckIn{TicketID: ticketId}
+	err = s.db.QueryRow(query, time.Now(), userID, eventId, ticketId).Scan(&checkIn.UserID, &checkIn.CheckedInAt)
+	if err == sql.ErrNoRows {
+		var checkedInAt time.Time
+		query = "SELECT checked_in_at FROM email_requests WHERE event_id = $1 AND ticket_id = $2 AND approved = true AND checked_in_at IS NOT NULL"
+		err = s.db.QueryRow(query, eventId, ticketId).Scan(&checkedInAt)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, utils.GetError("Ticket not found"))
+
+			return
+		}
+
+		if err == nil {
+			c.JSON(http.StatusConflict, utils.GetError("Ticket was already checked in at "+checkedInAt.Format(time.RFC3339)))
+
+			return
+		}
+	}
+	if err != nil {
+		log.Println("(CheckIn) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error checking in"))
+
+		return
+	}
+
+	err = s.db.QueryRow("SELECT name, email FROM users WHERE id = $1", checkIn.UserID).Scan(&checkIn.Name, &checkIn.Email)
+	if err != nil {
+		log.Println("(CheckIn) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving participant"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, checkIn)
+}
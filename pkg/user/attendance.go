@@ -0,0 +1,89 @@
+package user
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/utils"
+)
+
+// AttendanceColumns returns the SQL columns counting the attended events and
+// no-shows of the user in userIDColumn, scanned into Reliability.
+func AttendanceColumns(userIDColumn string) string {
+	count := func(attendance string) string {
+		return "(SELECT COUNT(*) FROM email_requests AS history WHERE history.requester_id = " + userIDColumn + " AND history.attendance = '" + attendance + "')"
+	}
+
+	return count(models.AttendanceAttended) + ", " + count(models.AttendanceNoShow)
+}
+
+// Reliability returns the share of attended events rounded to two decimals,
+// or nil when no attendance was marked yet.
+func Reliability(attended int, noShows int) *float64 {
+	if attended+noShows == 0 {
+		return nil
+	}
+
+	ratio := math.Round(float64(attended)/float64(attended+noShows)*100) / 100
+
+	return &ratio
+}
+
+// @Summary Get my attendance history
+// @Description Lists the started events the current user was approved for, newest first, with the attendance marked by the organizers
+// @Tags user
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.AttendanceRecord
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /user/me/attendance [get]
+func (s *UserService) GetMyAttendance(c *gin.Context) {
+	userID := c.GetString(constants.UserID_key)
+
+	query := `
+		SELECT email_requests.id, events.public_id, events.name, events.timezone, events.starts_at,
+			email_requests.attendance, email_requests.checked_in_at
+		FROM email_requests
+		JOIN events ON events.public_id = email_requests.event_id AND events.deleted_at IS NULL
+		WHERE email_requests.requester_id = $1 AND email_requests.approved = true AND events.starts_at < $2
+		ORDER BY events.starts_at DESC
+	`
+	rows, err := s.db.Query(query, userID, time.Now())
+	if err != nil {
+		log.Println("(GetMyAttendance) db.Query", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving attendance"))
+
+		return
+	}
+	defer rows.Close()
+
+	records := []models.AttendanceRecord{}
+	for rows.Next() {
+		var record models.AttendanceRecord
+		err := rows.Scan(&record.RequestID, &record.EventID, &record.EventName, &record.EventTimezone, &record.EventStartsAt, &record.Attendance, &record.CheckedInAt)
+		if err != nil {
+			log.Println("(GetMyAttendance) rows.Scan", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error processing attendance"))
+
+			return
+		}
+
+		record.EventStartsAt = utils.InTimezone(record.EventStartsAt, record.EventTimezone)
+		records = append(records, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println("(GetMyAttendance) rows.Err", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error reading attendance"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
@@ -30,7 +30,7 @@ func loginCheck(email string, password string, s *UserService) (string, error) {
 
 	u := models.User{}
 
-	err = s.db.QueryRow(`SELECT id, name, email, password, rating FROM users WHERE email = $1`, email).Scan(&u.ID, &u.Name, &u.Email, &u.Password, &u.Rating)
+	err = s.db.QueryRow(`SELECT id, name, email, password, rating FROM users WHERE email = $1 AND deleted_at IS NULL`, email).Scan(&u.ID, &u.Name, &u.Email, &u.Password, &u.Rating)
 	if err != nil {
 		return "", err
 	}
@@ -1,11 +1,14 @@
 package user
 
 import (
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/globus303/sportujspolu/constants"
 	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/utils"
 )
 
 // @Summary Get current user
@@ -20,7 +23,9 @@ func (s *UserService) GetMe(c *gin.Context) {
 
 	u := models.User{}
 
-	err := s.db.QueryRow(`SELECT id, name, email, rating FROM users WHERE ID = $1`, userID).Scan(&u.ID, &u.Name, &u.Email, &u.Rating)
+	var attended, noShows int
+	query := `SELECT id, name, email, rating, ` + AttendanceColumns("users.id") + ` FROM users WHERE ID = $1 AND deleted_at IS NULL`
+	err := s.db.QueryRow(query, userID).Scan(&u.ID, &u.Name, &u.Email, &u.Rating, &attended, &noShows)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 
@@ -28,17 +33,20 @@ func (s *UserService) GetMe(c *gin.Context) {
 	}
 
 	userResponse := models.PublicUser{
-		ID:     u.ID,
-		Name:   u.Name,
-		Email:  u.Email,
-		Rating: u.Rating,
+		ID:          u.ID,
+		Name:        u.Name,
+		Email:       u.Email,
+		Rating:      u.Rating,
+		Attended:    attended,
+		NoShows:     noShows,
+		Reliability: Reliability(attended, noShows),
 	}
 
 	c.JSON(http.StatusOK, userResponse)
 }
 
 // @Summary Delete current user
-// @Description Deletes the current user
+// @Description Deletes the current user together with the events they own, also the ones that already received requests and could not be deleted on their own. The account can be restored with POST /user/restore for 14 days, after 30 days it is purged for good.
 // @Tags user
 // @Security BearerAuth
 // @Produce  json
@@ -47,19 +55,136 @@ func (s *UserService) GetMe(c *gin.Context) {
 func (s *UserService) DeleteMe(c *gin.Context) {
 	userID := c.GetString(constants.UserID_key)
 
-	_, err := s.db.Exec(`DELETE FROM users WHERE ID = $1`, userID)
+	tx, err := s.db.Begin()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 
 		return
 	}
+	defer tx.Rollback()
 
-	_, err = s.db.Exec(`DELETE FROM events WHERE owner_id = $1`, userID)
+	// events share the timestamp of the user, so a restore brings back
+	// exactly the events deleted together with the account. Unlike
+	// DeleteEvent, events with requests are deleted too, leaving the account
+	// must not depend on other users
+	deletedAt := time.Now()
+
+	_, err = tx.Exec(`UPDATE users SET deleted_at = $1 WHERE ID = $2 AND deleted_at IS NULL`, deletedAt, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 
 		return
 	}
 
+	_, err = tx.Exec(`UPDATE events SET deleted_at = $1 WHERE owner_id = $2 AND deleted_at IS NULL`, deletedAt, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
 }
+
+// @Summary Restore deleted user
+// @Description Restores the account deleted in the last 14 days together with the events deleted with it, and logs the user in. Fails with 409 when the email was registered again in the meantime.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param input body LoginInput true "Login credentials"
+// @Success 200 {object} string
+// @Failure 400 {object} string
+// @Failure 409 {object} string
+// @Router /user/restore [post]
+func (s *UserService) RestoreMe(c *gin.Context) {
+	var input LoginInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	var userID, password string
+	// the email can be registered again after a delete, so several deleted
+	// accounts may share it, only the latest one can be restored
+	query := `
+		SELECT id, password FROM users
+		WHERE email = $1 AND deleted_at IS NOT NULL AND deleted_at > $2
+		ORDER BY deleted_at DESC
+		LIMIT 1
+	`
+	err := s.db.QueryRow(query, input.Email, time.Now().Add(-constants.RestoreWindow)).Scan(&userID, &password)
+	if err != nil {
+		log.Println("(RestoreMe) db.QueryRow", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no restorable account for these credentials."})
+
+		return
+	}
+
+	if err := verifyPassword(input.Password, password); err != nil {
+		log.Println("(RestoreMe) verifyPassword", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no restorable account for these credentials."})
+
+		return
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+	defer tx.Rollback()
+
+	// Register accepts the email of a deleted account, restoring it then
+	// would leave two active users with the same email
+	var taken bool
+	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE email = $1 AND deleted_at IS NULL)`, input.Email).Scan(&taken)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "the email is already used by another account."})
+
+		return
+	}
+
+	_, err = tx.Exec(`UPDATE events SET deleted_at = NULL WHERE owner_id = $1 AND deleted_at = (SELECT deleted_at FROM users WHERE id = $1)`, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	_, err = tx.Exec(`UPDATE users SET deleted_at = NULL WHERE id = $1`, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	token, err := utils.GenerateToken(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token": token})
+}
@@ -0,0 +1,427 @@
+package venues
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/globus303/sportujspolu/constants"
+	"github.com/globus303/sportujspolu/models"
+	"github.com/globus303/sportujspolu/pkg/roles"
+	"github.com/globus303/sportujspolu/utils"
+	"github.com/lib/pq"
+)
+
+// length limits of the venues columns, the name fits into events.location
+const (
+	maxNameLength       = 50
+	maxStreetLength     = 100
+	maxCityLength       = 50
+	maxPostalCodeLength = 12
+	maxFacilities       = 20
+	maxFacilityLength   = 30
+	defaultCountryCode  = "CZ"
+	maxVenuesLimit      = 100
+)
+
+const columns = "public_id, name, street, city, postal_code, country_code, latitude, longitude, facilities, indoor, owner_id, created_at, updated_at"
+
+func getColumnsForVenue(venue *models.Venue) []interface{} {
+	return []interface{}{&venue.ID, &venue.Name, &venue.Street, &venue.City, &venue.PostalCode, &venue.CountryCode,
+		&venue.Latitude, &venue.Longitude, pq.Array(&venue.Facilities), &venue.Indoor, &venue.OwnerID, &venue.CreatedAt, &venue.UpdatedAt}
+}
+
+// GetVenue returns the venue with the public ID, sql.ErrNoRows means it does
+// not exist.
+func GetVenue(db *sql.DB, venueId string) (*models.Venue, error) {
+	var venue models.Venue
+	if err := db.QueryRow("SELECT "+columns+" FROM venues WHERE public_id = $1", venueId).Scan(getColumnsForVenue(&venue)...); err != nil {
+		return nil, err
+	}
+
+	return &venue, nil
+}
+
+type VenuesService struct {
+	db *sql.DB
+}
+
+func NewVenuesService(db *sql.DB) *VenuesService {
+	return &VenuesService{db}
+}
+
+// normalizeFacilities lowercases the facilities and drops empty and repeated ones.
+func normalizeFacilities(facilities []string) []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, facility := range facilities {
+		facility = strings.Join(strings.Fields(strings.ToLower(facility)), " ")
+		if facility == "" || seen[facility] {
+			continue
+		}
+
+		seen[facility] = true
+		result = append(result, facility)
+	}
+
+	return result
+}
+
+// validateVenueInput trims and checks the venue, filling in the default country.
+func validateVenueInput(input *models.VenueInput) []models.FieldError {
+	fields := []models.FieldError{}
+	add := func(field string, message string) {
+		fields = append(fields, models.FieldError{Field: field, Message: message})
+	}
+
+	text := func(field string, value *string, maxLength int, required bool) {
+		*value = strings.TrimSpace(*value)
+
+		if *value == "" && required {
+			add(field, field+" is required")
+		} else if utf8.RuneCountInString(*value) > maxLength {
+			add(field, field+" must be at most "+strconv.Itoa(maxLength)+" characters")
+		}
+	}
+
+	text("name", &input.Name, maxNameLength, true)
+	text("street", &input.Street, maxStreetLength, false)
+	text("city", &input.City, maxCityLength, true)
+	text("postalCode", &input.PostalCode, maxPostalCodeLength, false)
+
+	input.CountryCode = strings.ToUpper(strings.TrimSpace(input.CountryCode))
+	if input.CountryCode == "" {
+		input.CountryCode = defaultCountryCode
+	}
+
+	if len(input.CountryCode) != 2 || strings.Trim(input.CountryCode, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		add("countryCode", "countryCode must be an ISO 3166-1 alpha-2 code such as CZ")
+	}
+
+	if err := utils.ValidateCoordinates(input.Latitude, input.Longitude); err != nil {
+		add("latitude", err.Error())
+	}
+
+	input.Facilities = normalizeFacilities(input.Facilities)
+	if len(input.Facilities) > maxFacilities {
+		add("facilities", "at most "+strconv.Itoa(maxFacilities)+" facilities are allowed")
+	}
+
+	for _, facility := range input.Facilities {
+		if utf8.RuneCountInString(facility) > maxFacilityLength {
+			add("facilities", "facilities must be at most "+strconv.Itoa(maxFacilityLength)+" characters each")
+
+			break
+		}
+	}
+
+	return fields
+}
+
+// @Summary Get venues
+// @Description Lists the venues ordered by name. All filters are optional and combined with AND.
+// @Tags venues
+// @Produce json
+// @Param q query string false "Case-insensitive substring of the name, street or city" example(hala)
+// @Param city query string false "City, case-insensitive exact match" example(Brno)
+// @Param indoor query bool false "Only indoor (true) or outdoor (false) venues"
+// @Param facilities query string false "Comma separated facilities, venues have to offer all of them" example(showers,parking)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of venues per page, at most 100" default(50)
+// @Success 200 {array} models.Venue
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /venues [get]
+func (s *VenuesService) GetVenues(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid page parameter"))
+
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > maxVenuesLimit {
+		c.JSON(http.StatusBadRequest, utils.GetError("Invalid limit parameter"))
+
+		return
+	}
+
+	conditions := []string{}
+	args := []interface{}{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if search := strings.TrimSpace(c.Query("q")); search != "" {
+		value := arg(strings.ToLower(search))
+		conditions = append(conditions, "(POSITION("+value+" IN LOWER(name)) > 0 OR POSITION("+value+" IN LOWER(street)) > 0 OR POSITION("+value+" IN LOWER(city)) > 0)")
+	}
+
+	if city := strings.TrimSpace(c.Query("city")); city != "" {
+		conditions = append(conditions, "LOWER(city) = LOWER("+arg(city)+")")
+	}
+
+	if value := c.Query("indoor"); value != "" {
+		indoor, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.GetError("Invalid indoor parameter"))
+
+			return
+		}
+
+		conditions = append(conditions, "indoor = "+arg(indoor))
+	}
+
+	if value := c.Query("facilities"); value != "" {
+		conditions = append(conditions, "facilities @> "+arg(pq.Array(normalizeFacilities(strings.Split(value, ",")))))
+	}
+
+	query := "SELECT " + columns + " FROM venues"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY LOWER(name), public_id LIMIT " + arg(limit) + " OFFSET " + arg((page-1)*limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		log.Println("(GetVenues) db.Query", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error retrieving venues"))
+
+		return
+	}
+	defer rows.Close()
+
+	venues := []models.Venue{}
+	for rows.Next() {
+		var venue models.Venue
+		if err := rows.Scan(getColumnsForVenue(&venue)...); err != nil {
+			log.Println("(GetVenues) rows.Scan", err)
+			c.JSON(http.StatusInternalServerError, utils.GetError("Error processing venues"))
+
+			return
+		}
+
+		venues = append(venues, venue)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println("(GetVenues) rows.Err", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error reading venues"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, venues)
+}
+
+// @Summary Get a venue
+// @Description Retrieves a single venue
+// @Tags venues
+// @Produce json
+// @Param venueId path string true "Venue ID" example(v7k2m9xq4pza)
+// @Success 200 {object} models.Venue
+// @Failure 404 {object} models.ErrorResponse
+// @Router /venues/{venueId} [get]
+func (s *VenuesService) GetSingleVenue(c *gin.Context) {
+	venue, err := GetVenue(s.db, c.Param("venueId"))
+	if err != nil {
+		log.Println("(GetSingleVenue) GetVenue", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Venue not found"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, venue)
+}
+
+// @Summary Create a venue
+// @Description Creates a venue events can reference instead of a free-text location. countryCode defaults to CZ, facilities are stored lowercased.
+// @Tags venues
+// @Accept json
+// @Produce json
+// @Param venue body models.VenueInput true "Venue object"
+// @Success 200 {object} models.Venue
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid venue, fields lists the problems"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /venues [post]
+func (s *VenuesService) CreateVenue(c *gin.Context) {
+	userID := c.GetString(constants.UserID_key)
+
+	var input models.VenueInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Println("(CreateVenue) c.ShouldBindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Error while parsing request body"))
+
+		return
+	}
+
+	if fields := validateVenueInput(&input); len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, utils.GetValidationError("Invalid venue", fields))
+
+		return
+	}
+
+	query := `
+		INSERT INTO venues (public_id, owner_id, name, street, city, postal_code, country_code, latitude, longitude, facilities, indoor)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING ` + columns
+	var venue models.Venue
+	err := s.db.QueryRow(query, utils.GenerateUUID(), userID, input.Name, input.Street, input.City, input.PostalCode, input.CountryCode,
+		input.Latitude, input.Longitude, pq.Array(input.Facilities), input.Indoor).Scan(getColumnsForVenue(&venue)...)
+	if err != nil {
+		log.Println("(CreateVenue) db.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error creating venue"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, venue)
+}
+
+// validateUserIsOwnerOfVenue writes the error response unless the current
+// user created the venue.
+func (s *VenuesService) validateUserIsOwnerOfVenue(c *gin.Context, venueId string) bool {
+	userID := c.GetString(constants.UserID_key)
+
+	var ownerID string
+	err := s.db.QueryRow("SELECT owner_id FROM venues WHERE public_id = $1", venueId).Scan(&ownerID)
+	if err != nil {
+		log.Println("(validateUserIsOwnerOfVenue) db.QueryRow", err)
+		c.JSON(http.StatusNotFound, utils.GetError("Venue not found"))
+
+		return false
+	}
+
+	if ownerID != userID {
+		c.JSON(http.StatusForbidden, utils.GetError("You are not the owner of this venue"))
+
+		return false
+	}
+
+	return true
+}
+
+// @Summary Update a venue
+// @Description Replaces the venue. Events at the venue organized by its creator get its new name and coordinates, events of other organizers keep theirs. Available only to the user who created it.
+// @Tags venues
+// @Accept json
+// @Produce json
+// @Param venueId path string true "Venue ID" example(v7k2m9xq4pza)
+// @Param venue body models.VenueInput true "Venue object"
+// @Success 200 {object} models.Venue
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid venue, fields lists the problems"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /venues/{venueId} [put]
+func (s *VenuesService) UpdateVenue(c *gin.Context) {
+	venueId := c.Param("venueId")
+
+	var input models.VenueInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Println("(UpdateVenue) c.ShouldBindJSON", err)
+		c.JSON(http.StatusBadRequest, utils.GetError("Error while parsing request body"))
+
+		return
+	}
+
+	if fields := validateVenueInput(&input); len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, utils.GetValidationError("Invalid venue", fields))
+
+		return
+	}
+
+	if !s.validateUserIsOwnerOfVenue(c, venueId) {
+		return
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Println("(UpdateVenue) db.Begin", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error updating venue"))
+
+		return
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE venues
+		SET name = $1, street = $2, city = $3, postal_code = $4, country_code = $5, latitude = $6, longitude = $7, facilities = $8, indoor = $9, updated_at = $10
+		WHERE public_id = $11
+		RETURNING ` + columns
+	var venue models.Venue
+	err = tx.QueryRow(query, input.Name, input.Street, input.City, input.PostalCode, input.CountryCode,
+		input.Latitude, input.Longitude, pq.Array(input.Facilities), input.Indoor, time.Now(), venueId).Scan(getColumnsForVenue(&venue)...)
+	if err != nil {
+		log.Println("(UpdateVenue) tx.QueryRow", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error updating venue"))
+
+		return
+	}
+
+	// the copies on the events keep search and distance filters in sync, any
+	// user can pick the venue, so only the events the venue owner organizes
+	// are rewritten
+	query = `
+		UPDATE events SET location = $1, latitude = $2, longitude = $3
+		WHERE venue_id = $4 AND public_id IN (
+			SELECT event_roles.event_id FROM event_roles WHERE event_roles.user_id = $5 AND event_roles.role IN ` + roles.OrganizerRoles + `
+		)
+	`
+	_, err = tx.Exec(query, venue.Name, venue.Latitude, venue.Longitude, venueId, c.GetString(constants.UserID_key))
+	if err != nil {
+		log.Println("(UpdateVenue) tx.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error updating venue"))
+
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("(UpdateVenue) tx.Commit", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error updating venue"))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, venue)
+}
+
+// @Summary Delete a venue
+// @Description Deletes the venue. Its events keep the venue name and coordinates as their free-text location. Available only to the user who created it.
+// @Tags venues
+// @Param venueId path string true "Venue ID" example(v7k2m9xq4pza)
+// @Success 200
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /venues/{venueId} [delete]
+func (s *VenuesService) DeleteVenue(c *gin.Context) {
+	venueId := c.Param("venueId")
+
+	if !s.validateUserIsOwnerOfVenue(c, venueId) {
+		return
+	}
+
+	if _, err := s.db.Exec("DELETE FROM venues WHERE public_id = $1", venueId); err != nil {
+		log.Println("(DeleteVenue) db.Exec", err)
+		c.JSON(http.StatusInternalServerError, utils.GetError("Error deleting venue"))
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -7,13 +7,24 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
+	// events carry IANA timezones, embed the database so hosts without one work
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 	"github.com/globus303/sportujspolu/middleware"
+	"github.com/globus303/sportujspolu/pkg/calendar"
+	"github.com/globus303/sportujspolu/pkg/comments"
 	"github.com/globus303/sportujspolu/pkg/events"
 	"github.com/globus303/sportujspolu/pkg/messages"
+	"github.com/globus303/sportujspolu/pkg/participants"
+	"github.com/globus303/sportujspolu/pkg/purge"
 	"github.com/globus303/sportujspolu/pkg/references"
+	"github.com/globus303/sportujspolu/pkg/roles"
+	"github.com/globus303/sportujspolu/pkg/storage"
+	"github.com/globus303/sportujspolu/pkg/tickets"
 	"github.com/globus303/sportujspolu/pkg/user"
+	"github.com/globus303/sportujspolu/pkg/venues"
 	adapter "github.com/gwatts/gin-adapter"
 	"github.com/joho/godotenv"
 	"github.com/jub0bs/fcors"
@@ -45,39 +56,122 @@ func startRouter(db *sql.DB) {
 	router.Use(adapter.Wrap(cors))
 	v1 := router.Group("/api/v1")
 
+	uploadsDir := os.Getenv("UPLOADS_DIR")
+	if uploadsDir == "" {
+		uploadsDir = "uploads"
+	}
+	router.Static("/uploads", uploadsDir)
+	uploadsStorage := storage.NewLocalStorage(uploadsDir, "/uploads")
+
+	purge.NewPurger(db, uploadsStorage).Start(time.Hour)
+
 	userService := user.NewUserService(db)
 
 	user := v1.Group("/user")
 	user.POST("/register", userService.Register)
 	user.POST("/login", userService.Login)
+	user.POST("/restore", userService.RestoreMe)
 
-	protectedUser := user.Group("").Use(middleware.JwtAuth())
+	protectedUser := user.Group("").Use(middleware.JwtAuth(), middleware.ActiveUser(db))
 	protectedUser.GET("/me", userService.GetMe)
 	protectedUser.DELETE("/me", userService.DeleteMe)
+	protectedUser.GET("/me/attendance", userService.GetMyAttendance)
+
+	calendarService := calendar.NewCalendarService(db)
+
+	protectedUser.POST("/me/calendar-token", calendarService.CreateCalendarToken)
+	protectedUser.DELETE("/me/calendar-token", calendarService.RevokeCalendarToken)
+	v1.GET("/calendar/:token", calendarService.GetCalendarFeed)
 
 	referencesService := references.NewReferencesService(db)
 
-	levels := v1.Group("/references")
-	levels.GET("/levels", referencesService.GetAllLevels)
+	references := v1.Group("/references")
+	references.GET("/levels", referencesService.GetAllLevels)
+	references.GET("/sports", referencesService.GetAllSports)
+	references.GET("/tags", referencesService.GetPopularTags)
+
+	venuesService := venues.NewVenuesService(db)
+
+	venues := v1.Group("/venues")
+	venues.GET("", venuesService.GetVenues)
+	venues.GET("/:venueId", venuesService.GetSingleVenue)
+
+	protectedVenues := venues.Group("").Use(middleware.JwtAuth(), middleware.ActiveUser(db))
+	protectedVenues.POST("", venuesService.CreateVenue)
+	protectedVenues.PUT("/:venueId", venuesService.UpdateVenue)
+	protectedVenues.DELETE("/:venueId", venuesService.DeleteVenue)
+
+	eventsService := events.NewEventsService(db, uploadsStorage)
 
-	eventsService := events.NewEventsService(db)
+	protectedUser.GET("/me/events", eventsService.GetMyEvents)
+	protectedUser.GET("/me/joined-events", eventsService.GetJoinedEvents)
+	protectedUser.GET("/me/events/export", eventsService.ExportMyEvents)
 
 	events := v1.Group("/events")
 	events.GET("", eventsService.GetAllEvents)
 	events.GET("/:eventId", eventsService.GetSingleEvent)
+	events.GET("/:eventId/ical", calendarService.GetEventICal)
 
 	protectedEvents := events.Group("")
-	protectedEvents.Use(middleware.JwtAuth())
+	protectedEvents.Use(middleware.JwtAuth(), middleware.ActiveUser(db))
 
 	protectedEvents.POST("", eventsService.CreateEvent)
+	protectedEvents.POST("/import", eventsService.ImportEvents)
 	protectedEvents.PUT("/:eventId", eventsService.UpdateEvent)
+	protectedEvents.PATCH("/:eventId", eventsService.PatchEvent)
 	protectedEvents.DELETE("/:eventId", eventsService.DeleteEvent)
+	protectedEvents.POST("/:eventId/restore", eventsService.RestoreEvent)
+	protectedEvents.POST("/:eventId/publish", eventsService.PublishEvent)
+	protectedEvents.POST("/:eventId/cancel", eventsService.CancelEvent)
+	protectedEvents.POST("/:eventId/complete", eventsService.CompleteEvent)
+	protectedEvents.PUT("/:eventId/occurrences/:occurrenceDate", eventsService.UpdateOccurrence)
+	protectedEvents.DELETE("/:eventId/occurrences/:occurrenceDate", eventsService.CancelOccurrence)
+	protectedEvents.PUT("/:eventId/cover", eventsService.UploadCoverImage)
+	protectedEvents.DELETE("/:eventId/cover", eventsService.DeleteCoverImage)
+	protectedEvents.POST("/:eventId/gallery", eventsService.UploadGalleryImages)
+	protectedEvents.DELETE("/:eventId/gallery/:imageId", eventsService.DeleteGalleryImage)
+	protectedEvents.POST("/:eventId/clone", eventsService.CloneEvent)
+	protectedEvents.GET("/:eventId/attendees/export", eventsService.ExportAttendees)
+
+	protectedTemplates := v1.Group("/templates").Use(middleware.JwtAuth(), middleware.ActiveUser(db))
+	protectedTemplates.GET("", eventsService.GetTemplates)
+	protectedTemplates.POST("", eventsService.CreateTemplate)
+	protectedTemplates.PUT("/:templateId", eventsService.UpdateTemplate)
+	protectedTemplates.DELETE("/:templateId", eventsService.DeleteTemplate)
+	protectedTemplates.POST("/:templateId/events", eventsService.CreateEventFromTemplate)
+
+	participantsService := participants.NewParticipantsService(db)
+
+	protectedEvents.GET("/:eventId/participants", participantsService.GetEventParticipants)
+	protectedEvents.POST("/:eventId/participants", participantsService.JoinEvent)
+	protectedEvents.DELETE("/:eventId/participants", participantsService.LeaveEvent)
+
+	rolesService := roles.NewRolesService(db)
+
+	protectedEvents.GET("/:eventId/roles", rolesService.GetEventRoles)
+	protectedEvents.POST("/:eventId/roles", rolesService.AddEventRole)
+	protectedEvents.DELETE("/:eventId/roles/:userId", rolesService.RemoveEventRole)
+
+	ticketsService := tickets.NewTicketsService(db)
+
+	protectedEvents.GET("/:eventId/ticket", ticketsService.GetTicket)
+	protectedEvents.GET("/:eventId/ticket/qr", ticketsService.GetTicketQR)
+	protectedEvents.POST("/:eventId/check-in", ticketsService.CheckIn)
+
+	commentsService := comments.NewCommentsService(db)
+
+	events.GET("/:eventId/comments", commentsService.GetComments)
+	protectedEvents.POST("/:eventId/comments", commentsService.CreateComment)
+	protectedEvents.PUT("/:eventId/comments/:commentId", commentsService.UpdateComment)
+	protectedEvents.DELETE("/:eventId/comments/:commentId", commentsService.DeleteComment)
+	protectedEvents.PUT("/:eventId/comments/:commentId/moderation", commentsService.ModerateComment)
 
 	messagesService := messages.NewMessagesService(db)
 
-	protectedMessages := v1.Group("/messages").Use(middleware.JwtAuth())
+	protectedMessages := v1.Group("/messages").Use(middleware.JwtAuth(), middleware.ActiveUser(db))
 	protectedMessages.POST("/email/request", messagesService.SendEmailRequest)
 	protectedMessages.PATCH("/email/:requestId/approve", messagesService.ApproveEmailRequest)
+	protectedMessages.PUT("/email/:requestId/attendance", messagesService.MarkAttendance)
 	protectedMessages.GET("/email/sent-user-requests", messagesService.GetAllSentEmailRequests)
 	protectedMessages.GET("/email/received-owner-requests", messagesService.GetAllReceivedOwnerEmailRequests)
 
@@ -14,3 +14,7 @@ func GetDefaultedError(err error, fallbackMessage string) models.ErrorResponse {
 func GetError(errMessage string) models.ErrorResponse {
 	return models.ErrorResponse{Error: errMessage}
 }
+
+func GetValidationError(errMessage string, fields []models.FieldError) models.ValidationErrorResponse {
+	return models.ValidationErrorResponse{Error: errMessage, Fields: fields}
+}
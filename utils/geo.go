@@ -0,0 +1,24 @@
+package utils
+
+import "errors"
+
+// ValidateCoordinates checks an optional latitude and longitude pair.
+func ValidateCoordinates(latitude, longitude *float64) error {
+	if (latitude == nil) != (longitude == nil) {
+		return errors.New("latitude and longitude must be provided together")
+	}
+
+	if latitude == nil {
+		return nil
+	}
+
+	if *latitude < -90 || *latitude > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+
+	if *longitude < -180 || *longitude > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+
+	return nil
+}
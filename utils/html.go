@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	lineBreakTagsRegexp = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li)\s*/?>`)
+	htmlTagRegexp       = regexp.MustCompile(`<[^>]*>`)
+	blankLinesRegexp    = regexp.MustCompile(`\n\s*\n\s*\n+`)
+	spacesRegexp        = regexp.MustCompile(`[ \t]+`)
+)
+
+// StripHTML turns stored description markup into plain text, keeping line
+// breaks from <br> and block tags.
+func StripHTML(value string) string {
+	text := lineBreakTagsRegexp.ReplaceAllString(value, "\n")
+	text = htmlTagRegexp.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = spacesRegexp.ReplaceAllString(text, " ")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = strings.Join(lines, "\n")
+
+	return strings.TrimSpace(blankLinesRegexp.ReplaceAllString(text, "\n\n"))
+}
+
+// DescriptionTags are the formatting tags kept in descriptions. The
+// db/17_update.sql migration cleans the stored descriptions with the same
+// list, keep the two in sync.
+var DescriptionTags = []string{"b", "strong", "i", "em", "u", "p", "br", "ul", "ol", "li"}
+
+var allowedTags = map[atom.Atom]bool{}
+
+func init() {
+	for _, tag := range DescriptionTags {
+		allowedTags[atom.Lookup([]byte(tag))] = true
+	}
+}
+
+// htmlTextEscaper escapes text nodes. Quotes only need escaping inside
+// attributes, escaping them in text would leave &#34; in the search index.
+var htmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// droppedTags lose their content too, not only the tag itself.
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Object: true,
+	atom.Embed: true, atom.Noscript: true, atom.Template: true, atom.Textarea: true,
+}
+
+// SanitizeHTML keeps only the allowlisted formatting tags of user supplied
+// markup. Attributes are removed, other tags are dropped keeping their text,
+// and unclosed tags are closed.
+func SanitizeHTML(value string) string {
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(value))
+	var builder strings.Builder
+	open := []atom.Atom{}
+	skipping := atom.Atom(0)
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == xhtml.ErrorToken {
+			break
+		}
+
+		token := tokenizer.Token()
+
+		if skipping != 0 {
+			if tokenType == xhtml.EndTagToken && token.DataAtom == skipping {
+				skipping = 0
+			}
+
+			continue
+		}
+
+		switch tokenType {
+		case xhtml.TextToken:
+			builder.WriteString(htmlTextEscaper.Replace(token.Data))
+
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if droppedTags[token.DataAtom] {
+				if tokenType == xhtml.StartTagToken {
+					skipping = token.DataAtom
+				}
+
+				continue
+			}
+
+			if !allowedTags[token.DataAtom] {
+				continue
+			}
+
+			if token.DataAtom == atom.Br {
+				builder.WriteString("<br>")
+
+				continue
+			}
+
+			builder.WriteString("<" + token.DataAtom.String() + ">")
+
+			if tokenType == xhtml.SelfClosingTagToken {
+				builder.WriteString("</" + token.DataAtom.String() + ">")
+			} else {
+				open = append(open, token.DataAtom)
+			}
+
+		case xhtml.EndTagToken:
+			// close the matching tag and whatever was left open inside it
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != token.DataAtom {
+					continue
+				}
+
+				for j := len(open) - 1; j >= i; j-- {
+					builder.WriteString("</" + open[j].String() + ">")
+				}
+				open = open[:i]
+
+				break
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		builder.WriteString("</" + open[i].String() + ">")
+	}
+
+	return strings.TrimSpace(builder.String())
+}
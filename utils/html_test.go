@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"<b>bold</b><br/>line", "<b>bold</b><br>line"},
+		{`<p onclick="alert(1)">text</p>`, "<p>text</p>"},
+		{"<script>alert(1)</script>text", "text"},
+		{`<a href="https://example.com">link</a>`, "link"},
+		{"<em>open", "<em>open</em>"},
+		{"1 < 2", "1 &lt; 2"},
+		{`"quoted" it's`, `"quoted" it's`},
+		{"a &amp; b", "a &amp; b"},
+	}
+
+	for _, test := range tests {
+		if got := SanitizeHTML(test.value); got != test.want {
+			t.Errorf("SanitizeHTML(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+// the migration cleaning stored descriptions has to keep the same tags as
+// SanitizeHTML
+func TestDescriptionTagsMatchMigration(t *testing.T) {
+	migration, err := os.ReadFile("../db/17_update.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := append([]string{}, DescriptionTags...)
+	sort.Strings(want)
+
+	matches := regexp.MustCompile(`\(\?!/\?\(([a-z|]+)\)`).FindAllStringSubmatch(string(migration), -1)
+	if len(matches) == 0 {
+		t.Fatal("no tag list found in db/17_update.sql")
+	}
+
+	for _, match := range matches {
+		tags := strings.Split(match[1], "|")
+		sort.Strings(tags)
+		if strings.Join(tags, ",") != strings.Join(want, ",") {
+			t.Errorf("db/17_update.sql keeps %v, DescriptionTags are %v", tags, want)
+		}
+	}
+}
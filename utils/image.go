@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// ResizeToFit scales the image down so it fits into maxWidth x maxHeight,
+// keeping the aspect ratio. Every target pixel is the average of the source
+// pixels it covers, which keeps downscaled photos smooth. Smaller images are
+// returned as they are.
+func ResizeToFit(src image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxWidth && height <= maxHeight {
+		return src
+	}
+
+	scale := math.Min(float64(maxWidth)/float64(width), float64(maxHeight)/float64(height))
+	targetWidth := int(math.Max(1, math.Round(float64(width)*scale)))
+	targetHeight := int(math.Max(1, math.Round(float64(height)*scale)))
+
+	source := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(source, source.Bounds(), src, bounds.Min, draw.Src)
+
+	target := image.NewRGBA(image.Rect(0, 0, targetWidth, targetHeight))
+	for y := 0; y < targetHeight; y++ {
+		sourceY0 := y * height / targetHeight
+		sourceY1 := int(math.Max(float64(sourceY0+1), float64((y+1)*height/targetHeight)))
+
+		for x := 0; x < targetWidth; x++ {
+			sourceX0 := x * width / targetWidth
+			sourceX1 := int(math.Max(float64(sourceX0+1), float64((x+1)*width/targetWidth)))
+
+			var r, g, b, a, count uint64
+			for sy := sourceY0; sy < sourceY1; sy++ {
+				offset := source.PixOffset(sourceX0, sy)
+				for sx := sourceX0; sx < sourceX1; sx++ {
+					r += uint64(source.Pix[offset])
+					g += uint64(source.Pix[offset+1])
+					b += uint64(source.Pix[offset+2])
+					a += uint64(source.Pix[offset+3])
+					offset += 4
+					count++
+				}
+			}
+
+			target.SetRGBA(x, y, color.RGBA{R: uint8(r / count), G: uint8(g / count), B: uint8(b / count), A: uint8(a / count)})
+		}
+	}
+
+	return target
+}
+
+// FlattenOnWhite removes transparency, which JPEG cannot store.
+func FlattenOnWhite(src image.Image) image.Image {
+	flattened := image.NewRGBA(src.Bounds())
+	draw.Draw(flattened, flattened.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flattened, flattened.Bounds(), src, src.Bounds().Min, draw.Over)
+
+	return flattened
+}
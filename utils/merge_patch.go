@@ -0,0 +1,28 @@
+package utils
+
+// MergePatch applies an RFC 7396 JSON Merge Patch to the target document:
+// null removes a member, objects are merged recursively and any other value
+// replaces the original one.
+func MergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+
+			continue
+		}
+
+		targetObject[key] = MergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
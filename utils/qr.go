@@ -0,0 +1,513 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// QR codes are encoded in byte mode with error correction level M, which
+// keeps codes readable when a phone screen is scratched or dimmed. Versions
+// 1 to 10 hold up to 213 bytes, plenty for ticket tokens.
+
+type qrVersion struct {
+	ecPerBlock int
+	// blocks lists the data codewords of every block
+	blocks    []int
+	alignment []int
+	remainder int
+}
+
+var qrVersions = []qrVersion{
+	{10, []int{16}, nil, 0},
+	{16, []int{28}, []int{6, 18}, 7},
+	{26, []int{44}, []int{6, 22}, 7},
+	{18, []int{32, 32}, []int{6, 26}, 7},
+	{24, []int{43, 43}, []int{6, 30}, 7},
+	{16, []int{27, 27, 27, 27}, []int{6, 34}, 7},
+	{18, []int{31, 31, 31, 31}, []int{6, 22, 38}, 0},
+	{22, []int{38, 38, 39, 39}, []int{6, 24, 42}, 0},
+	{22, []int{36, 36, 36, 37, 37}, []int{6, 26, 46}, 0},
+	{26, []int{43, 43, 43, 43, 44}, []int{6, 28, 50}, 0},
+}
+
+const (
+	qrQuietZone  = 4
+	qrModuleSize = 8
+	// format bits of error correction level M
+	qrLevelM = 0
+)
+
+var (
+	qrExp [512]byte
+	qrLog [256]int
+)
+
+func init() {
+	value := 1
+	for i := 0; i < 255; i++ {
+		qrExp[i] = byte(value)
+		qrLog[value] = i
+		value <<= 1
+		if value&0x100 != 0 {
+			value ^= 0x11d
+		}
+	}
+
+	for i := 255; i < len(qrExp); i++ {
+		qrExp[i] = qrExp[i-255]
+	}
+}
+
+func qrMultiply(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+
+	return qrExp[qrLog[a]+qrLog[b]]
+}
+
+// qrErrorCorrection returns the Reed-Solomon codewords of the data block.
+func qrErrorCorrection(data []byte, count int) []byte {
+	// generator polynomial (x - a^0)(x - a^1)...(x - a^(count-1)), highest
+	// coefficient first without the leading 1
+	generator := make([]byte, count)
+	generator[count-1] = 1
+	root := byte(1)
+	for i := 0; i < count; i++ {
+		for j := 0; j < count; j++ {
+			generator[j] = qrMultiply(generator[j], root)
+			if j+1 < count {
+				generator[j] ^= generator[j+1]
+			}
+		}
+		root = qrMultiply(root, 2)
+	}
+
+	result := make([]byte, count)
+	for _, value := range data {
+		factor := value ^ result[0]
+		copy(result, result[1:])
+		result[count-1] = 0
+		for i := range result {
+			result[i] ^= qrMultiply(generator[i], factor)
+		}
+	}
+
+	return result
+}
+
+type qrBits []bool
+
+func (b *qrBits) append(value int, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 == 1)
+	}
+}
+
+// qrCodewords encodes the data and interleaves the data and error
+// correction codewords of the blocks.
+func qrCodewords(data []byte, version int) []byte {
+	info := qrVersions[version-1]
+
+	capacity := 0
+	for _, size := range info.blocks {
+		capacity += size
+	}
+
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+
+	bits := qrBits{}
+	bits.append(0x4, 4)
+	bits.append(len(data), countBits)
+	for _, value := range data {
+		bits.append(int(value), 8)
+	}
+
+	terminator := capacity*8 - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+
+	for pad := 0xec; len(bits) < capacity*8; pad ^= 0xec ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	codewords := make([]byte, capacity)
+	for i, bit := range bits {
+		if bit {
+			codewords[i/8] |= 1 << (7 - i%8)
+		}
+	}
+
+	blocks := [][]byte{}
+	corrections := [][]byte{}
+	offset := 0
+	for _, size := range info.blocks {
+		block := codewords[offset : offset+size]
+		blocks = append(blocks, block)
+		corrections = append(corrections, qrErrorCorrection(block, info.ecPerBlock))
+		offset += size
+	}
+
+	result := []byte{}
+	for i := 0; i < info.blocks[len(info.blocks)-1]; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+
+	for i := 0; i < info.ecPerBlock; i++ {
+		for _, correction := range corrections {
+			result = append(result, correction[i])
+		}
+	}
+
+	return result
+}
+
+type qrMatrix struct {
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+func newQRMatrix(size int) *qrMatrix {
+	m := &qrMatrix{size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for i := range m.modules {
+		m.modules[i] = make([]bool, size)
+		m.function[i] = make([]bool, size)
+	}
+
+	return m
+}
+
+func (m *qrMatrix) set(x, y int, dark bool) {
+	m.modules[y][x] = dark
+	m.function[y][x] = true
+}
+
+func (m *qrMatrix) finder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= m.size || y >= m.size {
+				continue
+			}
+
+			distance := max(abs(dx), abs(dy))
+			m.set(x, y, distance != 2 && distance != 4)
+		}
+	}
+}
+
+func (m *qrMatrix) alignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			m.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+func (m *qrMatrix) format(mask int) {
+	data := qrLevelM<<3 | mask
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = remainder<<1 ^ (remainder>>9)*0x537
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		m.set(8, i, bit(i))
+	}
+	m.set(8, 7, bit(6))
+	m.set(8, 8, bit(7))
+	m.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		m.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		m.set(m.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		m.set(8, m.size-15+i, bit(i))
+	}
+	m.set(8, m.size-8, true)
+}
+
+func (m *qrMatrix) version(version int) {
+	if version < 7 {
+		return
+	}
+
+	remainder := version
+	for i := 0; i < 12; i++ {
+		remainder = remainder<<1 ^ (remainder>>11)*0x1f25
+	}
+	bits := version<<12 | remainder
+
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 == 1
+		a, b := m.size-11+i%3, i/3
+		m.set(a, b, dark)
+		m.set(b, a, dark)
+	}
+}
+
+func (m *qrMatrix) functionPatterns(version int) {
+	for i := 0; i < m.size; i++ {
+		m.set(6, i, i%2 == 0)
+		m.set(i, 6, i%2 == 0)
+	}
+
+	m.finder(3, 3)
+	m.finder(m.size-4, 3)
+	m.finder(3, m.size-4)
+
+	positions := qrVersions[version-1].alignment
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// the corners without a finder pattern only
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			m.alignment(x, y)
+		}
+	}
+
+	// reserve the format and version areas before the data goes in
+	m.format(0)
+	m.version(version)
+}
+
+func (m *qrMatrix) place(codewords []byte) {
+	i := 0
+	for right := m.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+
+		for vertical := 0; vertical < m.size; vertical++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vertical
+				if (right+1)&2 == 0 {
+					y = m.size - 1 - vertical
+				}
+
+				if !m.function[y][x] && i < len(codewords)*8 {
+					m.modules[y][x] = (codewords[i>>3]>>(7-i&7))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func qrMasked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// applyMask flips the data modules, applying it twice restores them.
+func (m *qrMatrix) applyMask(mask int) {
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			if !m.function[y][x] && qrMasked(mask, x, y) {
+				m.modules[y][x] = !m.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the matrix by the four rules of ISO/IEC 18004, the mask
+// with the lowest score is used.
+func (m *qrMatrix) penalty() int {
+	score := 0
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+
+	line := func(get func(i int) bool) {
+		run := 1
+		for i := 1; i <= m.size; i++ {
+			if i < m.size && get(i) == get(i-1) {
+				run++
+
+				continue
+			}
+
+			if run >= 5 {
+				score += 3 + run - 5
+			}
+			run = 1
+		}
+
+		for i := 0; i+len(finderLike[0]) <= m.size; i++ {
+			for _, pattern := range finderLike {
+				matches := true
+				for j, dark := range pattern {
+					if get(i+j) != dark {
+						matches = false
+
+						break
+					}
+				}
+
+				if matches {
+					score += 40
+				}
+			}
+		}
+	}
+
+	for y := 0; y < m.size; y++ {
+		line(func(i int) bool { return m.modules[y][i] })
+	}
+	for x := 0; x < m.size; x++ {
+		line(func(i int) bool { return m.modules[i][x] })
+	}
+
+	dark := 0
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			if m.modules[y][x] {
+				dark++
+			}
+
+			if x+1 < m.size && y+1 < m.size {
+				value := m.modules[y][x]
+				if m.modules[y][x+1] == value && m.modules[y+1][x] == value && m.modules[y+1][x+1] == value {
+					score += 3
+				}
+			}
+		}
+	}
+
+	deviation := abs(dark*100/(m.size*m.size) - 50)
+	score += deviation / 5 * 10
+
+	return score
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// EncodeQR returns the modules of the QR code for the data, true is dark.
+func EncodeQR(data []byte) ([][]bool, error) {
+	version := 0
+	for i, info := range qrVersions {
+		capacity := 0
+		for _, size := range info.blocks {
+			capacity += size
+		}
+
+		header := 2
+		if i+1 >= 10 {
+			header = 3
+		}
+
+		if len(data)+header <= capacity {
+			version = i + 1
+
+			break
+		}
+	}
+
+	if version == 0 {
+		return nil, errors.New("data is too long for a QR code")
+	}
+
+	m := newQRMatrix(17 + 4*version)
+	m.functionPatterns(version)
+	m.place(qrCodewords(data, version))
+
+	best, bestScore := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		m.applyMask(mask)
+		m.format(mask)
+		if score := m.penalty(); bestScore < 0 || score < bestScore {
+			best, bestScore = mask, score
+		}
+		m.applyMask(mask)
+	}
+
+	m.applyMask(best)
+	m.format(best)
+
+	return m.modules, nil
+}
+
+// QRCodePNG renders the data as a QR code PNG with the quiet zone around it.
+func QRCodePNG(data []byte) ([]byte, error) {
+	modules, err := EncodeQR(data)
+	if err != nil {
+		return nil, err
+	}
+
+	size := (len(modules) + 2*qrQuietZone) * qrModuleSize
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+
+			for dy := 0; dy < qrModuleSize; dy++ {
+				for dx := 0; dx < qrModuleSize; dx++ {
+					img.SetColorIndex((x+qrQuietZone)*qrModuleSize+dx, (y+qrQuietZone)*qrModuleSize+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
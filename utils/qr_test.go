@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// helloM2 is "HELLO" in byte mode as version 1-M with mask 2, taken from an
+// independent encoder
+var helloM2 = []string{
+	"#######....#..#######",
+	"#.....#...#.#.#.....#",
+	"#.###.#.##....#.###.#",
+	"#.###.#.#.#.#.#.###.#",
+	"#.###.#.##..#.#.###.#",
+	"#.....#.####..#.....#",
+	"#######.#.#.#.#######",
+	"........##...........",
+	"#.#####...##..#####..",
+	".##.##.#.######..##..",
+	"..#####.#...#.##.###.",
+	".##.#....######..##..",
+	".#.######...#..#..#.#",
+	"........#.#.#..#.#...",
+	"#######..###.#..#.##.",
+	"#.....#.#.#....#####.",
+	"#.###.#.##.#.#..#.##.",
+	"#.###.#.##.#####.#...",
+	"#.###.#.##..#.##..#..",
+	"#.....#..######.###..",
+	"#######.##..#...#.##.",
+}
+
+func renderModules(modules [][]bool) []string {
+	rows := make([]string, len(modules))
+	for y, row := range modules {
+		var line strings.Builder
+		for _, dark := range row {
+			if dark {
+				line.WriteByte('#')
+			} else {
+				line.WriteByte('.')
+			}
+		}
+		rows[y] = line.String()
+	}
+
+	return rows
+}
+
+func TestQRErrorCorrection(t *testing.T) {
+	// "HELLO WORLD" as version 1-M, the example of ISO/IEC 18004
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	if got := qrErrorCorrection(data, 10); !bytes.Equal(got, want) {
+		t.Errorf("qrErrorCorrection() = %v, want %v", got, want)
+	}
+}
+
+func TestQRMatrix(t *testing.T) {
+	m := newQRMatrix(21)
+	m.functionPatterns(1)
+	m.place(qrCodewords([]byte("HELLO"), 1))
+	m.applyMask(2)
+	m.format(2)
+
+	got := renderModules(m.modules)
+	for y := range helloM2 {
+		if got[y] != helloM2[y] {
+			t.Errorf("row %d = %s, want %s", y, got[y], helloM2[y])
+		}
+	}
+}
+
+func TestEncodeQRVersion(t *testing.T) {
+	tests := []struct {
+		length int
+		size   int
+	}{
+		{1, 21},
+		{14, 21},
+		{15, 25},
+		{213, 57},
+	}
+
+	for _, test := range tests {
+		modules, err := EncodeQR(bytes.Repeat([]byte("a"), test.length))
+		if err != nil {
+			t.Fatalf("EncodeQR(%d bytes): %v", test.length, err)
+		}
+
+		if len(modules) != test.size {
+			t.Errorf("EncodeQR(%d bytes) size = %d, want %d", test.length, len(modules), test.size)
+		}
+	}
+
+	if _, err := EncodeQR(bytes.Repeat([]byte("a"), 214)); err == nil {
+		t.Error("EncodeQR(214 bytes) succeeded, want an error")
+	}
+}
@@ -0,0 +1,332 @@
+package utils
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxRecurrencePeriods = 5000
+	rruleDateLayout      = "20060102"
+	rruleDateTimeLayout  = "20060102T150405Z"
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+type RecurrenceWeekday struct {
+	Weekday time.Weekday
+	// Nth is only used for MONTHLY rules, 1 is the first and -1 the last
+	// matching weekday of the month, 0 means every one of them
+	Nth int
+}
+
+// RecurrenceRule is the subset of an RFC 5545 RRULE the app supports:
+// DAILY, WEEKLY and MONTHLY frequencies with INTERVAL, COUNT, UNTIL, BYDAY
+// and BYMONTHDAY. Occurrences are whole days, matching events.date, the local
+// day of the event start.
+type RecurrenceRule struct {
+	Frequency  string
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []RecurrenceWeekday
+	ByMonthDay []int
+}
+
+func ParseRecurrenceRule(value string) (*RecurrenceRule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, errors.New("recurrence rule is empty")
+	}
+
+	rule := &RecurrenceRule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, found := strings.Cut(part, "=")
+		if !found || val == "" {
+			return nil, errors.New("invalid recurrence rule part " + part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Frequency = strings.ToUpper(val)
+			if rule.Frequency != "DAILY" && rule.Frequency != "WEEKLY" && rule.Frequency != "MONTHLY" {
+				return nil, errors.New("unsupported recurrence frequency " + val)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 {
+				return nil, errors.New("invalid recurrence INTERVAL")
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, errors.New("invalid recurrence COUNT")
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := time.Parse(rruleDateLayout, val)
+			if err != nil {
+				until, err = time.Parse(rruleDateTimeLayout, val)
+			}
+			if err != nil {
+				return nil, errors.New("invalid recurrence UNTIL, expected YYYYMMDD")
+			}
+			until = TruncateToDate(until)
+			rule.Until = &until
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				weekday, err := parseRecurrenceWeekday(day)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(val, ",") {
+				monthDay, err := strconv.Atoi(day)
+				if err != nil || monthDay == 0 || monthDay < -31 || monthDay > 31 {
+					return nil, errors.New("invalid recurrence BYMONTHDAY " + day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, monthDay)
+			}
+		case "WKST":
+			if strings.ToUpper(val) != "MO" {
+				return nil, errors.New("only WKST=MO is supported")
+			}
+		default:
+			return nil, errors.New("unsupported recurrence rule part " + key)
+		}
+	}
+
+	if rule.Frequency == "" {
+		return nil, errors.New("recurrence rule requires FREQ")
+	}
+
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, errors.New("recurrence rule cannot contain both COUNT and UNTIL")
+	}
+
+	if rule.Frequency == "DAILY" && (len(rule.ByDay) > 0 || len(rule.ByMonthDay) > 0) {
+		return nil, errors.New("BYDAY and BYMONTHDAY are not supported with FREQ=DAILY")
+	}
+
+	if rule.Frequency == "WEEKLY" {
+		if len(rule.ByMonthDay) > 0 {
+			return nil, errors.New("BYMONTHDAY is not supported with FREQ=WEEKLY")
+		}
+
+		for _, day := range rule.ByDay {
+			if day.Nth != 0 {
+				return nil, errors.New("ordinal BYDAY is only supported with FREQ=MONTHLY")
+			}
+		}
+	}
+
+	return rule, nil
+}
+
+func parseRecurrenceWeekday(value string) (RecurrenceWeekday, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return RecurrenceWeekday{}, errors.New("invalid recurrence BYDAY " + value)
+	}
+
+	weekday, ok := weekdays[value[len(value)-2:]]
+	if !ok {
+		return RecurrenceWeekday{}, errors.New("invalid recurrence BYDAY " + value)
+	}
+
+	result := RecurrenceWeekday{Weekday: weekday}
+	if ordinal := value[:len(value)-2]; ordinal != "" {
+		nth, err := strconv.Atoi(ordinal)
+		if err != nil || nth == 0 || nth < -5 || nth > 5 {
+			return RecurrenceWeekday{}, errors.New("invalid recurrence BYDAY " + value)
+		}
+		result.Nth = nth
+	}
+
+	return result, nil
+}
+
+func TruncateToDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Occurrences returns the dates of the series starting at start that fall
+// into [from, to], leaving out the exception dates. COUNT is applied before
+// exceptions are removed, as RFC 5545 requires.
+func (r *RecurrenceRule) Occurrences(start, from, to time.Time, exceptions []time.Time) []time.Time {
+	start, from, to = TruncateToDate(start), TruncateToDate(from), TruncateToDate(to)
+
+	excluded := map[time.Time]bool{}
+	for _, exception := range exceptions {
+		excluded[TruncateToDate(exception)] = true
+	}
+
+	occurrences := []time.Time{}
+	r.iterate(start, to, func(date time.Time) {
+		if !date.Before(from) && !excluded[date] {
+			occurrences = append(occurrences, date)
+		}
+	})
+
+	return occurrences
+}
+
+// End returns the date of the last occurrence, or nil for endless series.
+func (r *RecurrenceRule) End(start time.Time) *time.Time {
+	if r.Count == 0 && r.Until == nil {
+		return nil
+	}
+
+	var last *time.Time
+	limit := TruncateToDate(start).AddDate(100, 0, 0)
+	if r.Until != nil {
+		limit = *r.Until
+	}
+
+	r.iterate(TruncateToDate(start), limit, func(date time.Time) {
+		last = &date
+	})
+
+	return last
+}
+
+func (r *RecurrenceRule) iterate(start, to time.Time, yield func(time.Time)) {
+	if r.Until != nil && r.Until.Before(to) {
+		to = *r.Until
+	}
+
+	emitted := 0
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		candidates, periodStart := r.periodCandidates(start, period*r.Interval)
+		if periodStart.After(to) {
+			return
+		}
+
+		for _, date := range candidates {
+			if date.Before(start) {
+				continue
+			}
+
+			if date.After(to) {
+				return
+			}
+
+			yield(date)
+
+			emitted++
+			if r.Count > 0 && emitted >= r.Count {
+				return
+			}
+		}
+	}
+}
+
+func (r *RecurrenceRule) periodCandidates(start time.Time, offset int) ([]time.Time, time.Time) {
+	switch r.Frequency {
+	case "WEEKLY":
+		monday := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)).AddDate(0, 0, 7*offset)
+		if len(r.ByDay) == 0 {
+			return []time.Time{start.AddDate(0, 0, 7*offset)}, monday
+		}
+
+		candidates := []time.Time{}
+		for _, day := range r.ByDay {
+			candidates = append(candidates, monday.AddDate(0, 0, (int(day.Weekday)+6)%7))
+		}
+
+		return sortDates(candidates), monday
+	case "MONTHLY":
+		firstOfMonth := time.Date(start.Year(), start.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+
+		return r.monthCandidates(start, firstOfMonth), firstOfMonth
+	default:
+		date := start.AddDate(0, 0, offset)
+
+		return []time.Time{date}, date
+	}
+}
+
+// monthCandidates returns the days of the month matching the rule. When
+// both BYMONTHDAY and BYDAY are set a day has to match both, as in RFC 5545,
+// so BYDAY=FR;BYMONTHDAY=13 means every Friday the 13th.
+func (r *RecurrenceRule) monthCandidates(start, firstOfMonth time.Time) []time.Time {
+	daysInMonth := firstOfMonth.AddDate(0, 1, -1).Day()
+
+	monthDays := map[int]bool{}
+	for _, monthDay := range r.ByMonthDay {
+		if monthDay < 0 {
+			monthDay = daysInMonth + monthDay + 1
+		}
+		monthDays[monthDay] = true
+	}
+
+	weekDays := map[int]bool{}
+	for _, weekday := range r.ByDay {
+		matching := []int{}
+		for day := 1; day <= daysInMonth; day++ {
+			if firstOfMonth.AddDate(0, 0, day-1).Weekday() == weekday.Weekday {
+				matching = append(matching, day)
+			}
+		}
+
+		switch {
+		case weekday.Nth == 0:
+			for _, day := range matching {
+				weekDays[day] = true
+			}
+		case weekday.Nth > 0 && weekday.Nth <= len(matching):
+			weekDays[matching[weekday.Nth-1]] = true
+		case weekday.Nth < 0 && -weekday.Nth <= len(matching):
+			weekDays[matching[len(matching)+weekday.Nth]] = true
+		}
+	}
+
+	candidates := []time.Time{}
+	for day := 1; day <= daysInMonth; day++ {
+		var matches bool
+		switch {
+		case len(r.ByMonthDay) > 0 && len(r.ByDay) > 0:
+			matches = monthDays[day] && weekDays[day]
+		case len(r.ByMonthDay) > 0:
+			matches = monthDays[day]
+		case len(r.ByDay) > 0:
+			matches = weekDays[day]
+		default:
+			// months without the start day (e.g. the 31st) are skipped, as in RFC 5545
+			matches = day == start.Day()
+		}
+
+		if matches {
+			candidates = append(candidates, firstOfMonth.AddDate(0, 0, day-1))
+		}
+	}
+
+	return candidates
+}
+
+func sortDates(dates []time.Time) []time.Time {
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	unique := dates[:0]
+	for _, date := range dates {
+		if len(unique) == 0 || !date.Equal(unique[len(unique)-1]) {
+			unique = append(unique, date)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+
+	return parsed
+}
+
+func dates(values ...string) []time.Time {
+	result := []time.Time{}
+	for _, value := range values {
+		result = append(result, date(value))
+	}
+
+	return result
+}
+
+func TestRecurrenceOccurrences(t *testing.T) {
+	tests := []struct {
+		name       string
+		rule       string
+		start      string
+		from       string
+		to         string
+		exceptions []time.Time
+		want       []time.Time
+	}{
+		{"weekly", "FREQ=WEEKLY", "2024-01-02", "2024-01-02", "2024-01-23", nil,
+			dates("2024-01-02", "2024-01-09", "2024-01-16", "2024-01-23")},
+		{"weekly from a later date", "FREQ=WEEKLY", "2024-01-01", "2024-01-10", "2024-01-22", nil,
+			dates("2024-01-15", "2024-01-22")},
+		{"every other week on two days", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH", "2024-01-02", "2024-01-02", "2024-01-31", nil,
+			dates("2024-01-02", "2024-01-04", "2024-01-16", "2024-01-18", "2024-01-30")},
+		{"monthly on the start day skips short months", "FREQ=MONTHLY", "2024-01-31", "2024-01-01", "2024-06-30", nil,
+			dates("2024-01-31", "2024-03-31", "2024-05-31")},
+		{"monthly on the last friday", "FREQ=MONTHLY;BYDAY=-1FR", "2024-01-01", "2024-01-01", "2024-03-31", nil,
+			dates("2024-01-26", "2024-02-23", "2024-03-29")},
+		{"monthly on negative month day", "FREQ=MONTHLY;BYMONTHDAY=-1", "2024-01-01", "2024-01-01", "2024-03-31", nil,
+			dates("2024-01-31", "2024-02-29", "2024-03-31")},
+		{"monthly BYDAY and BYMONTHDAY intersect", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", "2024-01-01", "2024-01-01", "2024-12-31", nil,
+			dates("2024-09-13", "2024-12-13")},
+		{"count", "FREQ=DAILY;COUNT=3", "2024-01-01", "2024-01-01", "2024-12-31", nil,
+			dates("2024-01-01", "2024-01-02", "2024-01-03")},
+		{"until is inclusive", "FREQ=DAILY;UNTIL=20240104", "2024-01-01", "2024-01-01", "2024-12-31", nil,
+			dates("2024-01-01", "2024-01-02", "2024-01-03", "2024-01-04")},
+		{"exdate does not extend count", "FREQ=WEEKLY;COUNT=3", "2024-01-01", "2024-01-01", "2024-12-31", dates("2024-01-08"),
+			dates("2024-01-01", "2024-01-15")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, err := ParseRecurrenceRule(test.rule)
+			if err != nil {
+				t.Fatalf("ParseRecurrenceRule(%q): %v", test.rule, err)
+			}
+
+			got := rule.Occurrences(date(test.start), date(test.from), date(test.to), test.exceptions)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Occurrences() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceEnd(t *testing.T) {
+	tests := []struct {
+		rule  string
+		start string
+		want  *time.Time
+	}{
+		{"FREQ=WEEKLY;COUNT=3", "2024-01-01", &[]time.Time{date("2024-01-15")}[0]},
+		{"FREQ=MONTHLY;UNTIL=20240415", "2024-01-20", &[]time.Time{date("2024-03-20")}[0]},
+		{"FREQ=DAILY", "2024-01-01", nil},
+	}
+
+	for _, test := range tests {
+		rule, err := ParseRecurrenceRule(test.rule)
+		if err != nil {
+			t.Fatalf("ParseRecurrenceRule(%q): %v", test.rule, err)
+		}
+
+		got := rule.End(date(test.start))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("End(%q) = %v, want %v", test.rule, got, test.want)
+		}
+	}
+}
+
+func TestParseRecurrenceRuleErrors(t *testing.T) {
+	rules := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=DAILY;COUNT=2;UNTIL=20240101",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+	}
+
+	for _, value := range rules {
+		if _, err := ParseRecurrenceRule(value); err == nil {
+			t.Errorf("ParseRecurrenceRule(%q) succeeded, want an error", value)
+		}
+	}
+}
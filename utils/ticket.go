@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+)
+
+var (
+	ErrInvalidTicket = errors.New("invalid ticket")
+	// ErrTicketEvent means a genuine ticket was scanned at another event
+	ErrTicketEvent = errors.New("ticket is for another event")
+)
+
+func ticketSignature(eventId string, ticketId string) (string, error) {
+	secret := os.Getenv("TICKET_SECRET")
+	if secret == "" {
+		return "", errors.New("TICKET_SECRET is not set")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("ticket:" + eventId + ":" + ticketId))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// SignTicket returns the ticket token encoded in the QR code, short enough
+// to keep the code easy to scan.
+func SignTicket(eventId string, ticketId string) (string, error) {
+	signature, err := ticketSignature(eventId, ticketId)
+	if err != nil {
+		return "", err
+	}
+
+	return eventId + "." + ticketId + "." + signature, nil
+}
+
+// VerifyTicket checks the signature of the token and returns its ticket ID.
+func VerifyTicket(token string, eventId string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidTicket
+	}
+
+	signature, err := ticketSignature(parts[0], parts[1])
+	if err != nil {
+		return "", err
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(parts[2])) {
+		return "", ErrInvalidTicket
+	}
+
+	if parts[0] != eventId {
+		return "", ErrTicketEvent
+	}
+
+	return parts[1], nil
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTicketRoundTrip(t *testing.T) {
+	t.Setenv("TICKET_SECRET", "test-secret")
+
+	token, err := SignTicket("q76j5d1a3xtn", "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ticketId, err := VerifyTicket(token, "q76j5d1a3xtn")
+	if err != nil || ticketId != "t1" {
+		t.Errorf("VerifyTicket() = %q, %v, want t1", ticketId, err)
+	}
+
+	if _, err := VerifyTicket(token, "otherevent01"); !errors.Is(err, ErrTicketEvent) {
+		t.Errorf("VerifyTicket() at another event = %v, want %v", err, ErrTicketEvent)
+	}
+
+	invalid := []string{
+		"",
+		"q76j5d1a3xtn.t1",
+		"q76j5d1a3xtn.t2." + token[len("q76j5d1a3xtn.t1."):],
+		token + "x",
+	}
+	for _, value := range invalid {
+		if _, err := VerifyTicket(value, "q76j5d1a3xtn"); !errors.Is(err, ErrInvalidTicket) {
+			t.Errorf("VerifyTicket(%q) = %v, want %v", value, err, ErrInvalidTicket)
+		}
+	}
+
+	t.Setenv("TICKET_SECRET", "other-secret")
+	if _, err := VerifyTicket(token, "q76j5d1a3xtn"); !errors.Is(err, ErrInvalidTicket) {
+		t.Errorf("VerifyTicket() with another secret = %v, want %v", err, ErrInvalidTicket)
+	}
+
+	t.Setenv("TICKET_SECRET", "")
+	if _, err := SignTicket("q76j5d1a3xtn", "t1"); err == nil {
+		t.Error("SignTicket() without a secret succeeded, want an error")
+	}
+}
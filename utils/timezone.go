@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultTimezone is used for events created without a timezone.
+const DefaultTimezone = "Europe/Prague"
+
+// LoadTimezone loads an IANA timezone, an empty name means DefaultTimezone.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		name = DefaultTimezone
+	}
+
+	// "Local" would depend on the server the API happens to run on
+	if name == "Local" {
+		return nil, errors.New("unknown time zone Local")
+	}
+
+	return time.LoadLocation(name)
+}
+
+// InTimezone renders t in the named timezone, unknown names leave t as it is.
+func InTimezone(t time.Time, name string) time.Time {
+	location, err := LoadTimezone(name)
+	if err != nil {
+		return t
+	}
+
+	return t.In(location)
+}
+
+// OnDay moves start to the given day keeping its wall clock time in its own
+// location, so occurrences of a series stay at the same local time across
+// daylight saving changes. Only the date part of day is used.
+func OnDay(start time.Time, day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+}